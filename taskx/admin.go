@@ -0,0 +1,374 @@
+package taskx
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed admin.html
+var adminDashboard []byte
+
+// TaskInfo 已注册任务的概要
+type TaskInfo struct {
+	ID          string   `json:"id"`
+	Type        TaskType `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Queue       string   `json:"queue"`
+	Tags        []string `json:"tags,omitempty"`
+	// NextRun 定时任务的下一次触发时间
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Paused  bool       `json:"paused"`
+}
+
+// ListTasks 返回本实例注册的任务, 按任务ID排序
+func (tm *TaskManager) ListTasks(ctx context.Context) ([]TaskInfo, error) {
+	tm.mu.RLock()
+
+	infos := make([]TaskInfo, 0, len(tm.tasks))
+	for id, task := range tm.tasks {
+		cfg := baseConfigOf(task.GetConfig())
+		info := TaskInfo{
+			ID:          id,
+			Type:        task.GetType(),
+			Description: cfg.Description,
+			Queue:       tm.taskQueue(task),
+			Tags:        cfg.Tags,
+		}
+		if entry, ok := tm.schedules[id]; ok && !entry.next.IsZero() {
+			next := entry.next
+			info.NextRun = &next
+		}
+		infos = append(infos, info)
+	}
+	tm.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	for i := range infos {
+		paused, err := tm.IsTaskPaused(ctx, infos[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		infos[i].Paused = paused
+	}
+	return infos, nil
+}
+
+// WorkerInfo 集群中的 Worker
+type WorkerInfo struct {
+	ID            string    `json:"id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	// Alive 心跳未过期
+	Alive bool `json:"alive"`
+	// Local 为 true 表示属于当前实例, Running 为其正在执行的任务数
+	Local   bool `json:"local"`
+	Running int  `json:"running"`
+}
+
+// ListWorkers 根据注册表与心跳列出集群中的 Worker, 心跳过期但尚未被回收的 Worker 也会列出
+func (tm *TaskManager) ListWorkers(ctx context.Context) ([]WorkerInfo, error) {
+	ids, err := tm.broker.DueMembers(ctx, tm.keyManager.WorkerRegistryKey(),
+		time.Now().Add(time.Second*defaultHeartbeatTTL), defaultWorkerListMax)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := tm.allWorkers()
+	local := make(map[string]*Worker, len(workers))
+	for _, w := range workers {
+		local[w.id] = w
+	}
+
+	infos := make([]WorkerInfo, 0, len(ids))
+	for _, id := range ids {
+		info := WorkerInfo{ID: id}
+		value, err := tm.broker.Get(ctx, tm.keyManager.WorkerHeartbeatKey(id))
+		switch {
+		case err == nil:
+			if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+				info.LastHeartbeat = time.Unix(ts, 0)
+				info.Alive = true
+			}
+		case !errors.Is(err, ErrKeyNotFound):
+			return nil, err
+		}
+		if w, ok := local[id]; ok {
+			info.Local = true
+			info.Running = len(w.runningJobs())
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos, nil
+}
+
+// AdminHandler 返回管理接口与控制台, 可挂载到任意前缀下:
+//
+//	http.Handle("/taskx/", http.StripPrefix("/taskx", tm.AdminHandler()))
+//
+// 接口没有鉴权, 需要由调用方在外层保护
+func (tm *TaskManager) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", tm.adminDashboard)
+	mux.Handle("/metrics", tm.MetricsHandler())
+	mux.HandleFunc("/api/tasks", tm.adminTasks)
+	mux.HandleFunc("/api/tasks/", tm.adminTask)
+	mux.HandleFunc("/api/queues", tm.adminQueues)
+	mux.HandleFunc("/api/queues/", tm.adminQueue)
+	mux.HandleFunc("/api/workers", tm.adminWorkers)
+	mux.HandleFunc("/api/results", tm.adminResults)
+	mux.HandleFunc("/api/jobs/", tm.adminJob)
+	mux.HandleFunc("/api/deadletters", tm.adminDeadLetters)
+	mux.HandleFunc("/api/deadletters/", tm.adminDeadLetter)
+	return mux
+}
+
+func (tm *TaskManager) adminDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(adminDashboard)
+}
+
+func (tm *TaskManager) adminTasks(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	tasks, err := tm.ListTasks(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+// adminTask 处理 POST /api/tasks/{id}/pause 与 POST /api/tasks/{id}/resume
+func (tm *TaskManager) adminTask(w http.ResponseWriter, r *http.Request) {
+	id, action := splitAction(strings.TrimPrefix(r.URL.Path, "/api/tasks/"))
+	adminPauseAction(w, r, action, func(ctx context.Context) error {
+		return tm.PauseTask(ctx, id)
+	}, func(ctx context.Context) error {
+		return tm.ResumeTask(ctx, id)
+	})
+}
+
+func (tm *TaskManager) adminQueues(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	depths, err := tm.QueueDepths(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, depths)
+}
+
+// adminQueue 处理 POST /api/queues/{name}/pause 与 POST /api/queues/{name}/resume
+func (tm *TaskManager) adminQueue(w http.ResponseWriter, r *http.Request) {
+	name, action := splitAction(strings.TrimPrefix(r.URL.Path, "/api/queues/"))
+	adminPauseAction(w, r, action, func(ctx context.Context) error {
+		return tm.PauseQueue(ctx, name)
+	}, func(ctx context.Context) error {
+		return tm.ResumeQueue(ctx, name)
+	})
+}
+
+func adminPauseAction(w http.ResponseWriter, r *http.Request, action string, pause, resume func(context.Context) error) {
+	var fn func(context.Context) error
+	switch action {
+	case "pause":
+		fn = pause
+	case "resume":
+		fn = resume
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := fn(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (tm *TaskManager) adminWorkers(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	workers, err := tm.ListWorkers(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, workers)
+}
+
+func (tm *TaskManager) adminResults(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	offset, limit := pageOf(r)
+	results, err := tm.ListRecentResults(r.Context(), offset, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// adminJob 处理 GET /api/jobs/{id} 与 POST /api/jobs/{id}/cancel
+func (tm *TaskManager) adminJob(w http.ResponseWriter, r *http.Request) {
+	id, action := splitAction(strings.TrimPrefix(r.URL.Path, "/api/jobs/"))
+	switch action {
+	case "":
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+		status, err := tm.GetStatus(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	case "cancel":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		if err := tm.Cancel(r.Context(), id); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (tm *TaskManager) adminDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	offset, limit := pageOf(r)
+	total, err := tm.CountDeadLetters(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	letters, err := tm.ListDeadLetters(r.Context(), offset, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total": total,
+		"items": letters,
+	})
+}
+
+// adminDeadLetter 处理 GET/DELETE /api/deadletters/{id} 与 POST /api/deadletters/{id}/requeue
+func (tm *TaskManager) adminDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id, action := splitAction(strings.TrimPrefix(r.URL.Path, "/api/deadletters/"))
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		dl, err := tm.GetDeadLetter(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, dl)
+	case action == "" && r.Method == http.MethodDelete:
+		removed, err := tm.PurgeDeadLetters(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if removed == 0 {
+			writeError(w, ErrDeadLetterNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case action == "requeue":
+		if !allowMethod(w, r, http.MethodPost) {
+			return
+		}
+		job, err := tm.RequeueDeadLetter(r.Context(), id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	case action == "":
+		w.Header().Set("Allow", "GET, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// splitAction 把 "{id}/{action}" 拆分为 id 与 action
+func splitAction(path string) (string, string) {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// pageOf 读取 offset 与 limit 参数, limit 默认 50, 最大 500
+func pageOf(r *http.Request) (int, int) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	return offset, limit
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError 把已知错误映射为对应的状态码
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrJobNotFound), errors.Is(err, ErrTaskNotFound),
+		errors.Is(err, ErrDeadLetterNotFound), errors.Is(err, ErrQueueNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrJobFinished):
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
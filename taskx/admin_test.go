@@ -0,0 +1,114 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminHandler(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "ok", Description: "always succeeds"},
+		execute:        func(ctx context.Context) error { return nil },
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "bad", RetryCount: -1},
+		execute:        func(ctx context.Context) error { return errors.New("boom") },
+	}))
+	okJob, err := tm.Enqueue(ctx, "ok")
+	assert.NoError(t, err)
+	_, err = tm.Enqueue(ctx, "bad")
+	assert.NoError(t, err)
+	delayed, err := tm.EnqueueIn(ctx, "ok", time.Hour)
+	assert.NoError(t, err)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		n, _ := tm.CountDeadLetters(ctx)
+		results, _ := tm.ListRecentResults(ctx, 0, 10)
+		return n == 1 && len(results) == 2
+	})
+
+	server := httptest.NewServer(http.StripPrefix("/taskx", tm.AdminHandler()))
+	defer server.Close()
+	call := func(method, path string, out interface{}) int {
+		req, _ := http.NewRequest(method, server.URL+"/taskx"+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+		}
+		return resp.StatusCode
+	}
+
+	var tasks []map[string]interface{}
+	assert.Equal(t, http.StatusOK, call("GET", "/api/tasks", &tasks))
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "ok", tasks[1]["id"])
+		assert.Equal(t, "once", tasks[1]["type"])
+	}
+
+	var queues []QueueDepth
+	assert.Equal(t, http.StatusOK, call("GET", "/api/queues", &queues))
+	assert.Equal(t, []QueueDepth{{Queue: DefaultQueue, Pending: 0, Delayed: 1}}, queues)
+	assert.Equal(t, http.StatusNoContent, call("POST", "/api/queues/default/pause", nil))
+	assert.Equal(t, http.StatusOK, call("GET", "/api/queues", &queues))
+	assert.True(t, queues[0].Paused)
+	assert.Equal(t, http.StatusNoContent, call("POST", "/api/queues/default/resume", nil))
+	assert.Equal(t, http.StatusNotFound, call("POST", "/api/queues/missing/pause", nil))
+	assert.Equal(t, http.StatusNoContent, call("POST", "/api/tasks/ok/pause", nil))
+	assert.Equal(t, http.StatusOK, call("GET", "/api/tasks", &tasks))
+	assert.Equal(t, true, tasks[1]["paused"])
+	assert.Equal(t, http.StatusNoContent, call("POST", "/api/tasks/ok/resume", nil))
+
+	var workers []WorkerInfo
+	assert.Equal(t, http.StatusOK, call("GET", "/api/workers", &workers))
+	if assert.Len(t, workers, 1) {
+		assert.True(t, workers[0].Alive)
+		assert.True(t, workers[0].Local)
+	}
+
+	var results []JobStatus
+	assert.Equal(t, http.StatusOK, call("GET", "/api/results", &results))
+	assert.Len(t, results, 2)
+
+	var status JobStatus
+	assert.Equal(t, http.StatusOK, call("GET", "/api/jobs/"+okJob.ID, &status))
+	assert.Equal(t, TaskStatusCompleted, status.Status)
+	assert.Equal(t, http.StatusConflict, call("POST", "/api/jobs/"+okJob.ID+"/cancel", nil))
+	assert.Equal(t, http.StatusNoContent, call("POST", "/api/jobs/"+delayed.ID+"/cancel", nil))
+	assert.Equal(t, http.StatusNotFound, call("GET", "/api/jobs/missing", nil))
+
+	var page struct {
+		Total int64         `json:"total"`
+		Items []*DeadLetter `json:"items"`
+	}
+	assert.Equal(t, http.StatusOK, call("GET", "/api/deadletters", &page))
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, int64(1), page.Total)
+		var job Job
+		assert.Equal(t, http.StatusOK, call("POST", "/api/deadletters/"+page.Items[0].ID+"/requeue", &job))
+		assert.Equal(t, "bad", job.TaskID)
+		assert.Equal(t, http.StatusNotFound, call("DELETE", "/api/deadletters/"+page.Items[0].ID, nil))
+	}
+	assert.Equal(t, http.StatusMethodNotAllowed, call("DELETE", "/api/tasks", nil))
+
+	resp, err := http.Get(server.URL + "/taskx/")
+	if assert.NoError(t, err) {
+		defer resp.Body.Close()
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html"))
+	}
+}
@@ -0,0 +1,325 @@
+package taskx
+
+import (
+	"context"
+	"time"
+)
+
+// AutoscaleConfig 自动伸缩配置, 只调整处理所有队列的共享 Worker, 队列专属 Worker 数量保持不变
+// 扩容时先增大协程池, 达到上限后再增加 Worker; 缩容顺序相反
+type AutoscaleConfig struct {
+	MinWorkers  int
+	MaxWorkers  int
+	MinPoolSize int
+	MaxPoolSize int
+	// Interval 评估间隔, 默认 5 秒
+	Interval time.Duration
+	// TargetLatency 期望的派发延迟, 观测到的平均派发延迟或按执行耗时估算的排队时间超过该值时扩容
+	// 为 0 时只在所有名额都被占用且仍有任务排队时扩容
+	TargetLatency time.Duration
+	// ScaleDownDelay 队列为空且利用率低于一半持续该时间后缩容一步, 默认 30 秒
+	ScaleDownDelay time.Duration
+}
+
+func (c *AutoscaleConfig) normalize() *AutoscaleConfig {
+	if c == nil {
+		return nil
+	}
+	cfg := *c
+	if cfg.MinWorkers < 1 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+	if cfg.MinPoolSize < 1 {
+		cfg.MinPoolSize = 1
+	}
+	if cfg.MaxPoolSize < cfg.MinPoolSize {
+		cfg.MaxPoolSize = cfg.MinPoolSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second * defaultAutoscaleInterval
+	}
+	if cfg.ScaleDownDelay <= 0 {
+		cfg.ScaleDownDelay = time.Second * defaultScaleDownDelay
+	}
+	return &cfg
+}
+
+type ScaleDirection int
+
+const (
+	ScaleUp ScaleDirection = iota
+	ScaleDown
+)
+
+func (d ScaleDirection) String() string {
+	if d == ScaleUp {
+		return "up"
+	}
+	return "down"
+}
+
+// ScaleEvent 一次伸缩, 通过 ScaleHook 通知
+type ScaleEvent struct {
+	Direction    ScaleDirection
+	PrevWorkers  int
+	Workers      int
+	PrevPoolSize int
+	PoolSize     int
+	// 触发伸缩时的观测值
+	Pending     int64
+	Active      int
+	MeanLatency time.Duration
+	MeanExec    time.Duration
+	Time        time.Time
+}
+
+// scaleInput 一次评估的观测值
+type scaleInput struct {
+	pending     int64
+	active      int
+	capacity    int
+	meanLatency time.Duration
+	meanExec    time.Duration
+}
+
+// shouldScaleUp 仍有任务排队, 且名额已满或延迟超出目标
+func (c *AutoscaleConfig) shouldScaleUp(in scaleInput) bool {
+	if in.pending == 0 {
+		return false
+	}
+	if in.active >= in.capacity {
+		return true
+	}
+	if c.TargetLatency <= 0 {
+		return false
+	}
+	if in.meanLatency > c.TargetLatency {
+		return true
+	}
+	// 按平均执行耗时估算积压任务的排队时间
+	expected := time.Duration(in.pending) * in.meanExec / time.Duration(in.capacity)
+	return expected > c.TargetLatency
+}
+
+// shouldScaleDown 没有任务排队且利用率不足一半
+func (c *AutoscaleConfig) shouldScaleDown(in scaleInput) bool {
+	if in.pending > 0 || in.active*2 > in.capacity {
+		return false
+	}
+	return c.TargetLatency <= 0 || in.meanLatency <= c.TargetLatency
+}
+
+// scaleUp 返回扩容一步后的 Worker 数量与协程池大小, 协程池每次翻倍
+func (c *AutoscaleConfig) scaleUp(workers, poolSize int) (int, int) {
+	if poolSize < c.MaxPoolSize {
+		return workers, clampInt(poolSize*2, c.MinPoolSize, c.MaxPoolSize)
+	}
+	return clampInt(workers+1, c.MinWorkers, c.MaxWorkers), poolSize
+}
+
+// scaleDown 返回缩容一步后的 Worker 数量与协程池大小, 协程池每次减半
+func (c *AutoscaleConfig) scaleDown(workers, poolSize int) (int, int) {
+	if workers > c.MinWorkers {
+		return workers - 1, poolSize
+	}
+	return workers, clampInt(poolSize/2, c.MinPoolSize, c.MaxPoolSize)
+}
+
+// autoscaler 周期性地根据队列深度与延迟调整本实例的共享 Worker, 各实例独立伸缩
+func (tm *TaskManager) autoscaler() {
+	ticker := time.NewTicker(tm.autoscale.Interval)
+	defer ticker.Stop()
+
+	prev := tm.metrics.Snapshot()
+	idleSince := time.Time{}
+	var meanExec time.Duration
+
+	for {
+		select {
+		case <-tm.ctx.Done():
+			return
+		case now := <-ticker.C:
+			snapshot := tm.metrics.Snapshot()
+			latency, exec := meanDurations(prev, snapshot)
+			prev = snapshot
+			// 本周期没有任务结束时沿用上一次的平均执行耗时
+			if exec > 0 {
+				meanExec = exec
+			}
+
+			in, err := tm.scaleInput(tm.ctx)
+			if err != nil {
+				continue
+			}
+			in.meanLatency = latency
+			in.meanExec = meanExec
+
+			switch {
+			case tm.autoscale.shouldScaleUp(in):
+				idleSince = time.Time{}
+				tm.scale(ScaleUp, in, now)
+			case tm.autoscale.shouldScaleDown(in):
+				if idleSince.IsZero() {
+					idleSince = now
+				}
+				if now.Sub(idleSince) >= tm.autoscale.ScaleDownDelay {
+					idleSince = now
+					tm.scale(ScaleDown, in, now)
+				}
+			default:
+				idleSince = time.Time{}
+			}
+		}
+	}
+}
+
+// meanDurations 计算两次快照之间的平均派发延迟与平均执行耗时
+func meanDurations(prev, cur *MetricsSnapshot) (time.Duration, time.Duration) {
+	var latencySum, execSum float64
+	var latencyCount, execCount uint64
+	for queue, h := range cur.Latencies {
+		latencySum += h.Sum - prev.Latencies[queue].Sum
+		latencyCount += h.Count - prev.Latencies[queue].Count
+	}
+	for id, t := range cur.Tasks {
+		execSum += t.Duration.Sum - prev.Tasks[id].Duration.Sum
+		execCount += t.Duration.Count - prev.Tasks[id].Duration.Count
+	}
+
+	var latency, exec time.Duration
+	if latencyCount > 0 {
+		latency = time.Duration(latencySum / float64(latencyCount) * float64(time.Second))
+	}
+	if execCount > 0 {
+		exec = time.Duration(execSum / float64(execCount) * float64(time.Second))
+	}
+	return latency, exec
+}
+
+// scaleInput 读取未暂停队列的积压数量与共享 Worker 的占用情况
+func (tm *TaskManager) scaleInput(ctx context.Context) (scaleInput, error) {
+	var in scaleInput
+	depths, err := tm.QueueDepths(ctx)
+	if err != nil {
+		return in, err
+	}
+	for _, d := range depths {
+		if !d.Paused {
+			in.pending += d.Pending
+		}
+	}
+
+	for _, w := range tm.workerList() {
+		if w.shared {
+			in.active += len(w.runningJobs())
+			in.capacity += w.PoolSize()
+		}
+	}
+	return in, nil
+}
+
+// scale 伸缩一步并通知 ScaleHook, 已达到边界时不做任何事
+func (tm *TaskManager) scale(direction ScaleDirection, in scaleInput, now time.Time) {
+	tm.workersMu.Lock()
+	prevWorkers, prevPoolSize := 0, tm.poolSize
+	for _, w := range tm.workers {
+		if w.shared {
+			prevWorkers++
+		}
+	}
+	workers, poolSize := tm.autoscale.scaleUp(prevWorkers, prevPoolSize)
+	if direction == ScaleDown {
+		workers, poolSize = tm.autoscale.scaleDown(prevWorkers, prevPoolSize)
+	}
+	if workers == prevWorkers && poolSize == prevPoolSize {
+		tm.workersMu.Unlock()
+		return
+	}
+
+	tm.poolSize = poolSize
+	tm.workerSize = workers
+	for _, w := range tm.workers {
+		if w.shared {
+			w.setPoolSize(poolSize)
+		}
+	}
+	for i := prevWorkers; i < workers; i++ {
+		worker := tm.newSharedWorker()
+		tm.workers = append(tm.workers, worker)
+		go worker.Start(tm.execCtx)
+	}
+	var retired []*Worker
+	for i := prevWorkers; i > workers; i-- {
+		retired = append(retired, tm.removeSharedWorkerLocked())
+	}
+	tm.workersMu.Unlock()
+
+	for _, w := range retired {
+		tm.retireWorker(w)
+	}
+
+	event := &ScaleEvent{
+		Direction:    direction,
+		PrevWorkers:  prevWorkers,
+		Workers:      workers,
+		PrevPoolSize: prevPoolSize,
+		PoolSize:     poolSize,
+		Pending:      in.pending,
+		Active:       in.active,
+		MeanLatency:  in.meanLatency,
+		MeanExec:     in.meanExec,
+		Time:         now,
+	}
+	tm.triggerHooks(func(h TaskHook) error {
+		if sh, ok := h.(ScaleHook); ok {
+			return sh.OnScale(event)
+		}
+		return nil
+	})
+}
+
+// removeSharedWorkerLocked 移出最后加入的共享 Worker, 移出后调度协程不再向它派发
+func (tm *TaskManager) removeSharedWorkerLocked() *Worker {
+	for i := len(tm.workers) - 1; i >= 0; i-- {
+		if w := tm.workers[i]; w.shared {
+			tm.workers = append(tm.workers[:i:i], tm.workers[i+1:]...)
+			tm.retiring = append(tm.retiring, w)
+			return w
+		}
+	}
+	return nil
+}
+
+// retireWorker 停止 Worker 领取任务, 已派发未开始的任务放回队列, 执行中的任务结束后注销
+func (tm *TaskManager) retireWorker(w *Worker) {
+	w.Stop()
+	go func() {
+		<-w.doneCh
+		w.requeueBuffered()
+		w.inflight.Wait()
+		w.stopHeartbeat()
+		w.deregister()
+
+		tm.workersMu.Lock()
+		for i, r := range tm.retiring {
+			if r == w {
+				tm.retiring = append(tm.retiring[:i:i], tm.retiring[i+1:]...)
+				break
+			}
+		}
+		tm.workersMu.Unlock()
+	}()
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
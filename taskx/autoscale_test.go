@@ -0,0 +1,109 @@
+package taskx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoscaleDecision(t *testing.T) {
+	cfg := (&AutoscaleConfig{MinWorkers: 1, MaxWorkers: 3, MinPoolSize: 2, MaxPoolSize: 8, TargetLatency: time.Second}).normalize()
+
+	assert.False(t, cfg.shouldScaleUp(scaleInput{pending: 0, active: 4, capacity: 4}))
+	assert.True(t, cfg.shouldScaleUp(scaleInput{pending: 1, active: 4, capacity: 4}))
+	assert.True(t, cfg.shouldScaleUp(scaleInput{pending: 1, active: 1, capacity: 4, meanLatency: 2 * time.Second}))
+	// 100 个积压任务 × 100ms / 4 个名额 = 2.5s
+	assert.True(t, cfg.shouldScaleUp(scaleInput{pending: 100, active: 1, capacity: 4, meanExec: 100 * time.Millisecond}))
+	assert.False(t, cfg.shouldScaleUp(scaleInput{pending: 10, active: 1, capacity: 4, meanExec: 100 * time.Millisecond}))
+
+	assert.True(t, cfg.shouldScaleDown(scaleInput{active: 2, capacity: 4}))
+	assert.False(t, cfg.shouldScaleDown(scaleInput{active: 3, capacity: 4}))
+	assert.False(t, cfg.shouldScaleDown(scaleInput{pending: 1, capacity: 4}))
+
+	workers, pool := 1, 2
+	var steps [][2]int
+	for i := 0; i < 5; i++ {
+		workers, pool = cfg.scaleUp(workers, pool)
+		steps = append(steps, [2]int{workers, pool})
+	}
+	assert.Equal(t, [][2]int{{1, 4}, {1, 8}, {2, 8}, {3, 8}, {3, 8}}, steps)
+
+	steps = nil
+	for i := 0; i < 5; i++ {
+		workers, pool = cfg.scaleDown(workers, pool)
+		steps = append(steps, [2]int{workers, pool})
+	}
+	assert.Equal(t, [][2]int{{2, 8}, {1, 8}, {1, 4}, {1, 2}, {1, 2}}, steps)
+}
+
+type scaleRecorder struct {
+	NoopTaskHook
+	mu     sync.Mutex
+	events []*ScaleEvent
+}
+
+func (h *scaleRecorder) OnScale(event *ScaleEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *scaleRecorder) last() *ScaleEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.events) == 0 {
+		return nil
+	}
+	return h.events[len(h.events)-1]
+}
+
+func TestTaskManagerAutoscale(t *testing.T) {
+	hook := &scaleRecorder{}
+	tm := newTestManager(t, WithPoolSize(1), WithHooks(hook), WithAutoscale(AutoscaleConfig{
+		MinWorkers:     1,
+		MaxWorkers:     2,
+		MinPoolSize:    1,
+		MaxPoolSize:    2,
+		Interval:       50 * time.Millisecond,
+		ScaleDownDelay: 200 * time.Millisecond,
+	}))
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "block"},
+		execute: func(ctx context.Context) error {
+			<-release
+			return nil
+		},
+	}))
+	for i := 0; i < 8; i++ {
+		_, err := tm.Enqueue(ctx, "block")
+		assert.NoError(t, err)
+	}
+
+	tm.Start()
+	waitFor(t, 10*time.Second, func() bool {
+		event := hook.last()
+		return event != nil && event.Workers == 2 && event.PoolSize == 2
+	})
+	assert.Equal(t, ScaleUp, hook.last().Direction)
+	waitFor(t, 5*time.Second, func() bool {
+		return tm.Metrics().Snapshot().ActiveJobs == 4
+	})
+
+	close(release)
+	waitFor(t, 10*time.Second, func() bool {
+		event := hook.last()
+		return event != nil && event.Workers == 1 && event.PoolSize == 1
+	})
+	assert.Equal(t, ScaleDown, hook.last().Direction)
+	assert.Len(t, tm.workerList(), 1)
+	waitFor(t, 5*time.Second, func() bool {
+		return len(tm.allWorkers()) == 1
+	})
+}
@@ -0,0 +1,116 @@
+package taskx
+
+import (
+	"context"
+	"time"
+)
+
+// Broker 抽象任务管理所需的存储与派发操作, 键名由 KeyManager 生成后传入
+// 内置 RedisBroker 与 MemoryBroker 两种实现, 每个方法都需要保证原子性
+type Broker interface {
+	// Push 把 id 放入队列头部
+	Push(ctx context.Context, queue, id string) error
+	// Peek 返回队列头部的至多 n 个元素, 不会移除
+	Peek(ctx context.Context, queue string, n int) ([]string, error)
+	// Len 返回队列长度
+	Len(ctx context.Context, queue string) (int64, error)
+	// Remove 从队列中移除一个 id
+	Remove(ctx context.Context, queue, id string) error
+	// Move 原子地把队列尾部(最早入队)的元素移入处理列表, 队列为空时返回 ErrQueueEmpty
+	Move(ctx context.Context, queue, processing string) (string, error)
+	// BlockingMove 与 Move 相同, 队列为空时最多阻塞 timeout 等待新元素, 超时返回 ErrQueueEmpty
+	BlockingMove(ctx context.Context, queue, processing string, timeout time.Duration) (string, error)
+	// Ack 从处理列表中确认移除一个 id
+	Ack(ctx context.Context, processing, id string) error
+	// RequeueAll 把处理列表中的全部元素放回队列, 返回移动的数量
+	RequeueAll(ctx context.Context, processing, queue string) (int, error)
+
+	// Schedule 以时间为分数把 id 放入有序集合(如延迟集合), 已存在时覆盖原有时间
+	Schedule(ctx context.Context, set, id string, at time.Time) error
+	// ScheduleIfAbsent 与 Schedule 相同, 但已存在时保留原有时间
+	ScheduleIfAbsent(ctx context.Context, set, id string, at time.Time) error
+	// Unschedule 从有序集合中移除 id
+	Unschedule(ctx context.Context, set, id string) error
+	// ScheduledLen 返回有序集合中的元素数量
+	ScheduledLen(ctx context.Context, set string) (int64, error)
+	// DueMembers 返回有序集合中不晚于 before 的至多 limit 个元素, 不会移除
+	DueMembers(ctx context.Context, set string, before time.Time, limit int) ([]string, error)
+	// PromoteDue 把延迟集合中不晚于 now 的至多 limit 个元素按到期顺序移入队列
+	PromoteDue(ctx context.Context, set, queue string, now time.Time, limit int) (int, error)
+	// EnqueueJob 原子地占用去重键与唯一键、写入任务记录并投递到队列或延迟集合
+	// 任一键已被占用时返回 false
+	EnqueueJob(ctx context.Context, args *EnqueueArgs) (bool, error)
+
+	// Get 读取键值, 键不存在时返回 ErrKeyNotFound; 心跳与任务记录都通过键值保存
+	Get(ctx context.Context, key string) (string, error)
+	// Set 写入键值, ttl 为 0 表示永不过期
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	// SetNX 仅在键不存在时写入, 用于获取锁与争抢触发
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// CompareAndDelete 仅当值与 value 一致时删除, 用于释放锁
+	CompareAndDelete(ctx context.Context, key, value string) (bool, error)
+	// CompareAndExpire 仅当值与 value 一致时刷新过期时间, 用于续期锁
+	CompareAndExpire(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+
+	// SetFields 合并写入多个字段并刷新过期时间, 用于保存任务状态
+	SetFields(ctx context.Context, key string, fields map[string]string, ttl time.Duration) error
+	// GetFields 读取全部字段, 键不存在时返回空 map
+	GetFields(ctx context.Context, key string) (map[string]string, error)
+
+	// ArchiveAdd 向按时间排序的归档写入一条记录, 超出 max 条时淘汰最早的记录
+	ArchiveAdd(ctx context.Context, key, id, data string, at time.Time, max int) error
+	// ArchiveList 按时间倒序分页读取归档记录
+	ArchiveList(ctx context.Context, key string, offset, limit int) ([]string, error)
+	// ArchiveGet 读取单条归档记录, 不存在时返回 ErrKeyNotFound
+	ArchiveGet(ctx context.Context, key, id string) (string, error)
+	// ArchiveRemove 删除指定记录, 不传 ids 时清空归档, 返回删除的数量
+	ArchiveRemove(ctx context.Context, key string, ids ...string) (int64, error)
+	ArchiveLen(ctx context.Context, key string) (int64, error)
+
+	// Allow 按限流规则尝试占用一次配额, 返回 0 表示放行, 否则为预计需要等待的时间
+	Allow(ctx context.Context, key string, limit *RateLimit, now time.Time) (time.Duration, error)
+
+	// AcquireSemaphores 以 token 同时占用多个信号量的名额, limits 为各信号量的容量, 名额在 ttl 后自动释放
+	// 任一信号量已满时不占用任何名额并返回 false
+	AcquireSemaphores(ctx context.Context, keys []string, limits []int, token string, ttl time.Duration) (bool, error)
+	// RenewSemaphores 延长 token 占用的名额, 任一名额已失效时返回 false
+	RenewSemaphores(ctx context.Context, keys []string, token string, ttl time.Duration) (bool, error)
+	ReleaseSemaphores(ctx context.Context, keys []string, token string) error
+
+	// AcquireLease 以 holder 身份争抢或续期租约, 成功时返回本次任期的 fencing token
+	// 租约被他人持有时返回 0; 同一 holder 续期时 token 不变, 租约失效后重新获得时 token 递增
+	AcquireLease(ctx context.Context, key, holder string, ttl time.Duration) (int64, error)
+	// ReleaseLease 仅在租约属于 holder 时释放
+	ReleaseLease(ctx context.Context, key, holder string) error
+
+	// Publish 向频道广播消息
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe 订阅频道, 返回的 channel 在 ctx 结束或订阅断开时关闭
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// EnqueueArgs 描述一次原子入队
+type EnqueueArgs struct {
+	JobID   string
+	JobKey  string
+	JobData string
+	// Queue 为 At 为零值时的目标队列, 否则为目标延迟集合
+	Queue string
+	At    time.Time
+	// DedupKey 为空时不做去重
+	DedupKey string
+	DedupTTL time.Duration
+	// UniqueKey 为空时不做唯一性检查, 与 DedupKey 任一已被占用时都不会入队
+	UniqueKey string
+	UniqueTTL time.Duration
+}
+
+// handOff 把处理列表中的 entry 交给 put 写到别处(队列、延迟集合或暂停列表), 写入成功后再从处理列表确认移除
+// put 失败时条目保留在处理列表中, 由 reaper 回收, 保证不会丢失
+func handOff(ctx context.Context, b Broker, processing, entry string, put func() error) error {
+	if err := put(); err != nil {
+		return err
+	}
+	return b.Ack(ctx, processing, entry)
+}
@@ -0,0 +1,370 @@
+package taskx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// brokerHarness 为 Broker 契约测试提供被测实现, elapse 让键的过期时间前进 d
+type brokerHarness struct {
+	broker Broker
+	elapse func(d time.Duration)
+}
+
+// testBrokerContract 所有 Broker 实现都必须满足的行为, 由各实现的测试以自身的 harness 调用
+func testBrokerContract(t *testing.T, newHarness func(t *testing.T) *brokerHarness) {
+	ctx := context.Background()
+
+	t.Run("Queue", func(t *testing.T) {
+		b := newHarness(t).broker
+
+		assert.NoError(t, b.Push(ctx, "q", "a"))
+		assert.NoError(t, b.Push(ctx, "q", "b"))
+		assert.NoError(t, b.Push(ctx, "q", "c"))
+
+		items, err := b.Peek(ctx, "q", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c", "b"}, items)
+
+		n, err := b.Len(ctx, "q")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		assert.NoError(t, b.Remove(ctx, "q", "b"))
+		items, _ = b.Peek(ctx, "q", 10)
+		assert.Equal(t, []string{"c", "a"}, items)
+	})
+
+	t.Run("Move", func(t *testing.T) {
+		b := newHarness(t).broker
+
+		_, err := b.Move(ctx, "q", "p")
+		assert.ErrorIs(t, err, ErrQueueEmpty)
+
+		for _, id := range []string{"a", "b", "c"} {
+			assert.NoError(t, b.Push(ctx, "q", id))
+		}
+		for _, want := range []string{"a", "b"} {
+			id, err := b.Move(ctx, "q", "p")
+			assert.NoError(t, err)
+			assert.Equal(t, want, id)
+		}
+		items, _ := b.Peek(ctx, "p", 10)
+		assert.Equal(t, []string{"b", "a"}, items)
+
+		assert.NoError(t, b.Ack(ctx, "p", "a"))
+		assert.NoError(t, b.Ack(ctx, "p", "missing"))
+		items, _ = b.Peek(ctx, "p", 10)
+		assert.Equal(t, []string{"b"}, items)
+
+		// 放回的元素排在队列尾部之前, 保持原来的领取顺序
+		assert.NoError(t, b.Push(ctx, "p", "d"))
+		n, err := b.RequeueAll(ctx, "p", "q")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		items, _ = b.Peek(ctx, "q", 10)
+		assert.Equal(t, []string{"d", "b", "c"}, items)
+		n, _ = b.RequeueAll(ctx, "p", "q")
+		assert.Zero(t, n)
+	})
+
+	t.Run("BlockingMove", func(t *testing.T) {
+		b := newHarness(t).broker
+
+		_, err := b.BlockingMove(ctx, "q", "p", 20*time.Millisecond)
+		assert.ErrorIs(t, err, ErrQueueEmpty)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			b.Push(ctx, "q", "a")
+		}()
+		id, err := b.BlockingMove(ctx, "q", "p", 5*time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", id)
+		items, _ := b.Peek(ctx, "p", 10)
+		assert.Equal(t, []string{"a"}, items)
+	})
+
+	t.Run("Delayed", func(t *testing.T) {
+		b := newHarness(t).broker
+		now := time.Now()
+
+		assert.NoError(t, b.Schedule(ctx, "d", "late", now.Add(time.Hour)))
+		assert.NoError(t, b.Schedule(ctx, "d", "first", now.Add(-2*time.Second)))
+		assert.NoError(t, b.Schedule(ctx, "d", "second", now.Add(-time.Second)))
+		assert.NoError(t, b.ScheduleIfAbsent(ctx, "d", "second", now.Add(time.Hour)))
+		assert.NoError(t, b.ScheduleIfAbsent(ctx, "d", "gone", now))
+		assert.NoError(t, b.Unschedule(ctx, "d", "gone"))
+
+		n, err := b.ScheduledLen(ctx, "d")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), n)
+
+		due, err := b.DueMembers(ctx, "d", now, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first"}, due)
+
+		promoted, err := b.PromoteDue(ctx, "d", "q", now, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, promoted)
+
+		items, _ := b.Peek(ctx, "q", 10)
+		assert.Equal(t, []string{"second", "first"}, items)
+
+		assert.NoError(t, b.Schedule(ctx, "d", "late", now))
+		promoted, _ = b.PromoteDue(ctx, "d", "q", now, 10)
+		assert.Equal(t, 1, promoted)
+		n, _ = b.ScheduledLen(ctx, "d")
+		assert.Zero(t, n)
+	})
+
+	t.Run("EnqueueJob", func(t *testing.T) {
+		h := newHarness(t)
+		b := h.broker
+
+		args := &EnqueueArgs{JobID: "1", JobKey: "job:1", JobData: "{}", Queue: "q", DedupKey: "dedup", DedupTTL: time.Minute}
+		ok, err := b.EnqueueJob(ctx, args)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = b.EnqueueJob(ctx, &EnqueueArgs{JobID: "2", JobKey: "job:2", Queue: "q", DedupKey: "dedup", DedupTTL: time.Minute})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		data, err := b.Get(ctx, "job:1")
+		assert.NoError(t, err)
+		assert.Equal(t, "{}", data)
+		owner, _ := b.Get(ctx, "dedup")
+		assert.Equal(t, "1", owner)
+
+		_, err = b.Get(ctx, "job:2")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		items, _ := b.Peek(ctx, "q", 10)
+		assert.Equal(t, []string{"1"}, items)
+
+		// 唯一键与去重键任一被占用都不入队, 也不占用另一个键
+		at := time.Now().Add(time.Hour)
+		ok, _ = b.EnqueueJob(ctx, &EnqueueArgs{JobID: "3", JobKey: "job:3", JobData: "{}", Queue: "d", At: at,
+			UniqueKey: "unique", UniqueTTL: 50 * time.Millisecond})
+		assert.True(t, ok)
+		ok, _ = b.EnqueueJob(ctx, &EnqueueArgs{JobID: "4", JobKey: "job:4", JobData: "{}", Queue: "q",
+			DedupKey: "dedup4", DedupTTL: time.Minute, UniqueKey: "unique", UniqueTTL: time.Minute})
+		assert.False(t, ok)
+		_, err = b.Get(ctx, "dedup4")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		due, _ := b.DueMembers(ctx, "d", at, 10)
+		assert.Equal(t, []string{"3"}, due)
+
+		h.elapse(100 * time.Millisecond)
+		ok, _ = b.EnqueueJob(ctx, &EnqueueArgs{JobID: "5", JobKey: "job:5", JobData: "{}", Queue: "q",
+			UniqueKey: "unique", UniqueTTL: time.Minute})
+		assert.True(t, ok)
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		h := newHarness(t)
+		b := h.broker
+
+		ok, _ := b.SetNX(ctx, "lock", "token", 20*time.Millisecond)
+		assert.True(t, ok)
+		ok, _ = b.SetNX(ctx, "lock", "other", time.Minute)
+		assert.False(t, ok)
+
+		ok, _ = b.CompareAndExpire(ctx, "lock", "other", time.Minute)
+		assert.False(t, ok)
+		ok, _ = b.CompareAndDelete(ctx, "lock", "other")
+		assert.False(t, ok)
+
+		h.elapse(30 * time.Millisecond)
+		ok, _ = b.CompareAndExpire(ctx, "lock", "token", time.Minute)
+		assert.False(t, ok, "expired lock must not be renewed")
+
+		ok, _ = b.SetNX(ctx, "lock", "token", 20*time.Millisecond)
+		assert.True(t, ok)
+		ok, _ = b.CompareAndExpire(ctx, "lock", "token", time.Minute)
+		assert.True(t, ok)
+		h.elapse(30 * time.Millisecond)
+		ok, _ = b.CompareAndDelete(ctx, "lock", "token")
+		assert.True(t, ok, "renewed lock must outlive its original ttl")
+		_, err := b.Get(ctx, "lock")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		h := newHarness(t)
+		b := h.broker
+
+		assert.NoError(t, b.Set(ctx, "k", "v", 0))
+		assert.NoError(t, b.Set(ctx, "short", "v", 20*time.Millisecond))
+		value, err := b.Get(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, "v", value)
+
+		h.elapse(30 * time.Millisecond)
+		_, err = b.Get(ctx, "short")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		assert.NoError(t, b.Del(ctx, "k", "missing"))
+		_, err = b.Get(ctx, "k")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("Fields", func(t *testing.T) {
+		h := newHarness(t)
+		b := h.broker
+
+		assert.NoError(t, b.SetFields(ctx, "s", map[string]string{"a": "1", "b": "2"}, time.Minute))
+		assert.NoError(t, b.SetFields(ctx, "s", map[string]string{"b": "3"}, time.Minute))
+
+		fields, err := b.GetFields(ctx, "s")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"a": "1", "b": "3"}, fields)
+
+		assert.NoError(t, b.SetFields(ctx, "short", map[string]string{"a": "1"}, 10*time.Millisecond))
+		h.elapse(20 * time.Millisecond)
+		fields, err = b.GetFields(ctx, "short")
+		assert.NoError(t, err)
+		assert.Empty(t, fields)
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		b := newHarness(t).broker
+		now := time.Now()
+
+		for i, id := range []string{"a", "b", "c"} {
+			assert.NoError(t, b.ArchiveAdd(ctx, "dead", id, "data-"+id, now.Add(time.Duration(i)*time.Second), 2))
+		}
+
+		n, _ := b.ArchiveLen(ctx, "dead")
+		assert.Equal(t, int64(2), n)
+
+		records, err := b.ArchiveList(ctx, "dead", 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"data-c", "data-b"}, records)
+		records, _ = b.ArchiveList(ctx, "dead", 1, 10)
+		assert.Equal(t, []string{"data-b"}, records)
+
+		_, err = b.ArchiveGet(ctx, "dead", "a")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		data, err := b.ArchiveGet(ctx, "dead", "c")
+		assert.NoError(t, err)
+		assert.Equal(t, "data-c", data)
+
+		removed, _ := b.ArchiveRemove(ctx, "dead", "b", "missing")
+		assert.Equal(t, int64(1), removed)
+
+		removed, _ = b.ArchiveRemove(ctx, "dead")
+		assert.Equal(t, int64(1), removed)
+		n, _ = b.ArchiveLen(ctx, "dead")
+		assert.Zero(t, n)
+	})
+
+	t.Run("Allow", func(t *testing.T) {
+		b := newHarness(t).broker
+		now := time.Now()
+
+		bucket := &RateLimit{Limit: 2, Window: time.Second}
+		for i := 0; i < 2; i++ {
+			wait, err := b.Allow(ctx, "bucket", bucket, now)
+			assert.NoError(t, err)
+			assert.Zero(t, wait)
+		}
+		wait, _ := b.Allow(ctx, "bucket", bucket, now)
+		assert.Equal(t, 500*time.Millisecond, wait)
+		wait, _ = b.Allow(ctx, "bucket", bucket, now.Add(500*time.Millisecond))
+		assert.Zero(t, wait)
+
+		window := &RateLimit{Limit: 2, Window: time.Second, Algorithm: SlidingWindow}
+		b.Allow(ctx, "window", window, now)
+		b.Allow(ctx, "window", window, now.Add(300*time.Millisecond))
+		wait, _ = b.Allow(ctx, "window", window, now.Add(400*time.Millisecond))
+		assert.Equal(t, 600*time.Millisecond, wait)
+		wait, _ = b.Allow(ctx, "window", window, now.Add(time.Second+time.Millisecond))
+		assert.Zero(t, wait)
+	})
+
+	t.Run("Semaphores", func(t *testing.T) {
+		h := newHarness(t)
+		b := h.broker
+		keys := []string{"task", "tag"}
+
+		ok, _ := b.AcquireSemaphores(ctx, keys, []int{2, 1}, "a", time.Minute)
+		assert.True(t, ok)
+
+		// tag 已满时 task 的名额也不会被占用
+		ok, _ = b.AcquireSemaphores(ctx, keys, []int{2, 1}, "b", time.Minute)
+		assert.False(t, ok)
+		ok, _ = b.AcquireSemaphores(ctx, []string{"task"}, []int{2}, "c", time.Minute)
+		assert.True(t, ok)
+
+		ok, _ = b.RenewSemaphores(ctx, keys, "a", time.Minute)
+		assert.True(t, ok)
+		ok, _ = b.RenewSemaphores(ctx, keys, "b", time.Minute)
+		assert.False(t, ok)
+
+		assert.NoError(t, b.ReleaseSemaphores(ctx, keys, "a"))
+		ok, _ = b.AcquireSemaphores(ctx, []string{"tag"}, []int{1}, "b", 10*time.Millisecond)
+		assert.True(t, ok)
+
+		// 过期的名额自动释放
+		h.elapse(20 * time.Millisecond)
+		ok, _ = b.AcquireSemaphores(ctx, []string{"tag"}, []int{1}, "d", time.Minute)
+		assert.True(t, ok)
+	})
+
+	t.Run("Lease", func(t *testing.T) {
+		h := newHarness(t)
+		b := h.broker
+
+		token, _ := b.AcquireLease(ctx, "lease", "a", 20*time.Millisecond)
+		assert.Equal(t, int64(1), token)
+		token, _ = b.AcquireLease(ctx, "lease", "b", 20*time.Millisecond)
+		assert.Equal(t, int64(0), token)
+		token, _ = b.AcquireLease(ctx, "lease", "a", 20*time.Millisecond)
+		assert.Equal(t, int64(1), token)
+
+		// 租约过期后他人获得新的 token
+		h.elapse(30 * time.Millisecond)
+		token, _ = b.AcquireLease(ctx, "lease", "b", time.Minute)
+		assert.Equal(t, int64(2), token)
+
+		assert.NoError(t, b.ReleaseLease(ctx, "lease", "a"))
+		token, _ = b.AcquireLease(ctx, "lease", "a", time.Minute)
+		assert.Equal(t, int64(0), token)
+
+		assert.NoError(t, b.ReleaseLease(ctx, "lease", "b"))
+		token, _ = b.AcquireLease(ctx, "lease", "a", time.Minute)
+		assert.Equal(t, int64(3), token)
+	})
+
+	t.Run("PubSub", func(t *testing.T) {
+		b := newHarness(t).broker
+		subCtx, cancel := context.WithCancel(ctx)
+
+		messages, err := b.Subscribe(subCtx, "ch")
+		if !assert.NoError(t, err) {
+			cancel()
+			return
+		}
+		assert.NoError(t, b.Publish(ctx, "ch", "hello"))
+		select {
+		case msg := <-messages:
+			assert.Equal(t, "hello", msg)
+		case <-time.After(time.Second):
+			t.Fatal("message not delivered")
+		}
+
+		cancel()
+		select {
+		case _, ok := <-messages:
+			assert.False(t, ok)
+		case <-time.After(time.Second):
+			t.Fatal("subscription not closed")
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package taskx
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// runningJob 记录本实例正在执行的任务, 用于响应取消请求
+type runningJob struct {
+	cancel    context.CancelFunc
+	cancelled int32
+}
+
+func (r *runningJob) isCancelled() bool {
+	return atomic.LoadInt32(&r.cancelled) == 1
+}
+
+func (tm *TaskManager) trackRunning(jobID string, cancel context.CancelFunc) *runningJob {
+	r := &runningJob{cancel: cancel}
+	tm.runningMu.Lock()
+	tm.running[jobID] = r
+	tm.runningMu.Unlock()
+	return r
+}
+
+func (tm *TaskManager) untrackRunning(jobID string, r *runningJob) {
+	tm.runningMu.Lock()
+	if tm.running[jobID] == r {
+		delete(tm.running, jobID)
+	}
+	tm.runningMu.Unlock()
+}
+
+// cancelRunning 取消本实例上正在执行的任务, 任务不在本实例执行时返回 false
+func (tm *TaskManager) cancelRunning(jobID string) bool {
+	tm.runningMu.Lock()
+	r, ok := tm.running[jobID]
+	tm.runningMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	atomic.StoreInt32(&r.cancelled, 1)
+	r.cancel()
+	return true
+}
+
+// Cancel 取消任务: 从所有队列与延迟集合中移除排队中的副本, 并通过 pub/sub 通知正在执行它的实例取消 ctx
+// 任务最终状态记为 TaskStatusCancelled; 任务已结束时返回 ErrJobFinished
+func (tm *TaskManager) Cancel(ctx context.Context, jobID string) error {
+	status, err := tm.GetStatus(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	switch status.Status {
+	case TaskStatusCompleted, TaskStatusFailed, TaskStatusTimeout, TaskStatusCancelled:
+		return ErrJobFinished
+	}
+
+	// 先写入取消标记, 已被派发但尚未开始执行的副本在执行前会检查该标记
+	if err := tm.broker.Set(ctx, tm.keyManager.JobCancelKey(jobID), "1", tm.statusTTL); err != nil {
+		return err
+	}
+
+	for _, q := range tm.queues {
+		if err := tm.broker.Remove(ctx, tm.keyManager.QueueKey(q.Name), jobID); err != nil {
+			return err
+		}
+		if err := tm.broker.Unschedule(ctx, tm.keyManager.QueueDelayedKey(q.Name), jobID); err != nil {
+			return err
+		}
+	}
+
+	if !tm.cancelRunning(jobID) {
+		if err := tm.broker.Publish(ctx, tm.keyManager.CancelChannel(), jobID); err != nil {
+			return err
+		}
+	}
+
+	// 未在执行的任务直接结束, 正在执行的任务由执行它的 Worker 记录最终状态
+	if status.Status == TaskStatusPending {
+		job, err := tm.loadJob(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		if job.TaskID == jobID && status.TaskID != "" {
+			job.TaskID = status.TaskID
+		}
+		tm.finishCancelled(ctx, job, "", nil)
+
+		// 持续任务的排队副本已被移除, 取消本次执行后按间隔重新安排
+		if job.ID == job.TaskID {
+			tm.mu.RLock()
+			task, exists := tm.tasks[job.TaskID]
+			tm.mu.RUnlock()
+			if exists {
+				tm.rearmContinuous(ctx, task)
+			}
+		}
+	}
+	return nil
+}
+
+// isCancelled 检查任务是否已被取消
+func (tm *TaskManager) isCancelled(ctx context.Context, jobID string) bool {
+	_, err := tm.broker.Get(ctx, tm.keyManager.JobCancelKey(jobID))
+	return err == nil
+}
+
+// finishCancelled 记录取消状态并清理任务, 工作流节点视为失败; result 为空表示任务尚未开始执行
+// 发起取消的实例与已领取该任务的 Worker 可能都会收尾, 只有抢到记录权的一方生效
+func (tm *TaskManager) finishCancelled(ctx context.Context, job *Job, workerID string, result *TaskResult) {
+	finishKey := tm.keyManager.JobCancelFinishKey(job.ID)
+	claimed, err := tm.broker.SetNX(ctx, finishKey, strconv.FormatInt(time.Now().Unix(), 10), tm.statusTTL)
+	if err == nil && !claimed {
+		return
+	}
+
+	if result == nil {
+		now := time.Now()
+		result = &TaskResult{
+			TaskID:    job.TaskID,
+			JobID:     job.ID,
+			Attempt:   job.Retried + 1,
+			Status:    TaskStatusCancelled,
+			StartTime: now,
+			EndTime:   now,
+			Error:     ErrJobCancelled,
+		}
+	}
+	tm.setStatus(ctx, job, TaskStatusCancelled, workerID, result)
+	tm.recordResult(ctx, job, workerID, result)
+	if job.Workflow != "" {
+		tm.advanceWorkflow(ctx, job, result)
+	}
+	tm.finishJob(ctx, job)
+
+	// 系统调度的任务以任务ID作为 JobID, 取消只作用于当前这一次执行
+	if job.ID == job.TaskID {
+		tm.broker.Del(ctx, tm.keyManager.JobCancelKey(job.ID), finishKey)
+	}
+}
+
+// canceller 订阅取消通知, 取消本实例上正在执行的任务; 订阅断开后自动重连
+func (tm *TaskManager) canceller() {
+	for tm.ctx.Err() == nil {
+		messages, err := tm.broker.Subscribe(tm.ctx, tm.keyManager.CancelChannel())
+		if err == nil {
+			for jobID := range messages {
+				tm.cancelRunning(jobID)
+			}
+		}
+
+		select {
+		case <-tm.ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskManagerCancel(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+
+	worker := NewTaskManagerWithBroker(broker, WithWorkerSize(1), WithPoolSize(2))
+	t.Cleanup(worker.Stop)
+	// 另一个实例发起取消, 通过 pub/sub 通知执行任务的实例
+	client := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(client.Stop)
+
+	stopped := make(chan error, 1)
+	block := &testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "block"},
+		execute: func(ctx context.Context) error {
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return ctx.Err()
+		},
+	}
+	assert.NoError(t, worker.RegisterTask(block))
+	assert.NoError(t, client.RegisterTask(block))
+
+	running, err := worker.Enqueue(ctx, "block")
+	assert.NoError(t, err)
+	delayed, err := client.EnqueueIn(ctx, "block", time.Hour)
+	assert.NoError(t, err)
+
+	worker.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := client.GetStatus(ctx, running.ID)
+		return err == nil && status.Status == TaskStatusRunning
+	})
+
+	assert.NoError(t, client.Cancel(ctx, running.ID))
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("running job was not cancelled")
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := client.GetStatus(ctx, running.ID)
+		return err == nil && status.Status == TaskStatusCancelled
+	})
+
+	// 排队中的任务直接从延迟集合移除
+	assert.NoError(t, client.Cancel(ctx, delayed.ID))
+	members, _ := broker.DueMembers(ctx, client.keyManager.TaskDelayedKey(), time.Now().Add(2*time.Hour), 10)
+	assert.Empty(t, members)
+	status, err := client.GetStatus(ctx, delayed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, status.Status)
+	assert.Equal(t, "block", status.TaskID)
+
+	assert.ErrorIs(t, client.Cancel(ctx, delayed.ID), ErrJobFinished)
+	assert.ErrorIs(t, client.Cancel(ctx, "missing"), ErrJobNotFound)
+}
+
+func TestTaskManagerCancelContinuous(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	assert.NoError(t, tm.RegisterTask(&configTask{
+		typ: TaskTypeContinuous,
+		cfg: &ContinuousTaskConfig{
+			BaseTaskConfig: BaseTaskConfig{
+				ID:         "poll",
+				RetryCount: 1,
+				Backoff:    &FixedBackoff{Interval: time.Hour},
+			},
+			Interval: time.Hour,
+		},
+		execute: func(ctx context.Context) error {
+			return errors.New("boom")
+		},
+	}))
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, "poll")
+		return err == nil && status.Status == TaskStatusPending && status.Result != nil
+	})
+
+	// 取消等待重试的这一次执行, 持续任务仍按间隔重新安排
+	assert.NoError(t, tm.Cancel(ctx, "poll"))
+	status, err := tm.GetStatus(ctx, "poll")
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, status.Status)
+	members, err := tm.broker.DueMembers(ctx, tm.keyManager.QueueDelayedKey(DefaultQueue), time.Now().Add(2*time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"poll"}, members)
+	assert.False(t, tm.isCancelled(ctx, "poll"))
+}
+
+func TestFinishCancelledOnce(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+	job := &Job{ID: "job-1", TaskID: "task"}
+
+	// 发起取消的实例与领取到任务的 Worker 都会收尾, 结果只记录一次
+	tm.finishCancelled(ctx, job, "", nil)
+	tm.finishCancelled(ctx, job, "worker", nil)
+
+	results, err := tm.ListRecentResults(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	status, err := tm.GetStatus(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusCancelled, status.Status)
+	assert.Empty(t, status.WorkerID)
+}
@@ -0,0 +1,69 @@
+package taskx
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// concurrencySlots 任务执行期间占用的分布式信号量, 包括任务自身与各标签的并发限制
+type concurrencySlots struct {
+	tm     *TaskManager
+	keys   []string
+	limits []int
+	token  string
+	ttl    time.Duration
+}
+
+// acquireSlots 占用任务及其标签的并发名额, 未配置并发限制时返回 nil
+// 任一名额已满时不占用任何名额, 返回 ErrConcurrencyLimited
+func (tm *TaskManager) acquireSlots(ctx context.Context, task Task) (*concurrencySlots, error) {
+	cfg := baseConfigOf(task.GetConfig())
+	slots := &concurrencySlots{
+		tm:    tm,
+		token: uuid.New().String(),
+		ttl:   tm.lockTTL,
+	}
+
+	if cfg.MaxConcurrency > 0 {
+		slots.keys = append(slots.keys, tm.keyManager.TaskSemaphoreKey(task.GetID()))
+		slots.limits = append(slots.limits, cfg.MaxConcurrency)
+	}
+
+	// 按标签排序, 保证多个实例以相同顺序检查
+	tags := append([]string(nil), cfg.Tags...)
+	sort.Strings(tags)
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		limit, ok := tm.tagLimits[tag]
+		if !ok || limit <= 0 || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		slots.keys = append(slots.keys, tm.keyManager.TagSemaphoreKey(tag))
+		slots.limits = append(slots.limits, limit)
+	}
+
+	if len(slots.keys) == 0 {
+		return nil, nil
+	}
+
+	ok, err := tm.broker.AcquireSemaphores(ctx, slots.keys, slots.limits, slots.token, slots.ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrConcurrencyLimited
+	}
+	return slots, nil
+}
+
+func (s *concurrencySlots) renew(ctx context.Context) (bool, error) {
+	return s.tm.broker.RenewSemaphores(ctx, s.keys, s.token, s.ttl)
+}
+
+func (s *concurrencySlots) release(ctx context.Context) error {
+	return s.tm.broker.ReleaseSemaphores(ctx, s.keys, s.token)
+}
@@ -3,12 +3,37 @@ package taskx
 const (
 	DefaultNamespace = "taskx"
 	KeySeparator     = ":"
+	DefaultQueue     = "default"
 
 	defaultWorkerSize        = 4
 	defaultWorkerPool        = 10
 	defaultHeartbeatTTL      = 60  // seconds
 	defaultHeartbeatInterval = 5   // seconds
-	defaultLockTimeout       = 300 // seconds
+	defaultLockTimeout       = 30  // seconds, 执行期间由看门狗续期
 	defaultRetryDelay        = 100 // milliseconds
 	defaultRetryCount        = 3
+	defaultRetryMaxDelay     = 600 // seconds
+	defaultDeadLetterMax     = 10000
+	defaultRecentResultMax   = 1000
+	defaultStatusTTL         = 86400 // seconds
+	defaultScheduleInterval  = 1     // seconds
+	defaultScheduleClaimTTL  = 600   // seconds
+	defaultPromoteInterval   = 1     // seconds
+	defaultPromoteBatch      = 100
+	defaultReapInterval      = 15 // seconds
+	defaultReapBatch         = 100
+	defaultWorkerListMax     = 1000
+	defaultDedupTTL          = 86400 // seconds
+	defaultConcurrencyDelay  = 1     // seconds, 并发名额已满时延后派发的时间
+	defaultLockRetryDelay    = 1     // seconds, 获取任务锁时 Broker 异常延后派发的时间
+	defaultLeaderLease       = 15    // seconds
+	defaultAutoscaleInterval = 5     // seconds
+	defaultScaleDownDelay    = 30    // seconds
+	defaultDispatchInterval  = 1     // seconds
+	defaultShutdownGrace     = 1     // seconds, 关闭超时后等待被取消的任务放回队列的时间
+
+	// 运行中工作流状态的保留时间, 每次更新时刷新
+	defaultWorkflowTTL      = 604800 // seconds
+	defaultWorkflowAttempts = 3
+	defaultWorkflowBackoff  = 100 // milliseconds, 工作流更新失败后重试的间隔基数
 )
@@ -0,0 +1,313 @@
+package taskx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 描述任务的触发计划
+type Schedule interface {
+	// Next 返回晚于 t 的下一次触发时间, 无法触发时返回零值
+	Next(t time.Time) time.Time
+}
+
+// cronSchedule 基于位图的 cron 计划, 每个字段用一个 uint64 表示允许的取值
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// domStar/dowStar 标记日与周字段是否为通配, 用于决定两者的组合方式
+	domStar, dowStar bool
+	location         *time.Location
+}
+
+// everySchedule 固定间隔计划, 按 Unix 纪元对齐以保证多实例计算出相同的触发时间
+type everySchedule struct {
+	interval time.Duration
+}
+
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	secondBounds = cronBounds{min: 0, max: 59}
+	minuteBounds = cronBounds{min: 0, max: 59}
+	hourBounds   = cronBounds{min: 0, max: 23}
+	domBounds    = cronBounds{min: 1, max: 31}
+	monthBounds  = cronBounds{min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 周字段允许 0-7, 其中 0 和 7 都表示周日
+	dowBounds = cronBounds{min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ParseCron 解析 cron 表达式, 支持:
+//   - 5 段: 分 时 日 月 周
+//   - 6 段: 秒 分 时 日 月 周
+//   - 宏: @yearly @annually @monthly @weekly @daily @midnight @hourly @every <duration>
+//   - 时区前缀: TZ=Asia/Shanghai 或 CRON_TZ=Asia/Shanghai
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("%w: empty spec", ErrInvalidCron)
+	}
+
+	var location *time.Location
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("%w: missing fields after time zone", ErrInvalidCron)
+		}
+		name := spec[strings.Index(spec, "=")+1 : i]
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidCron, name)
+		}
+		location = loc
+		spec = strings.TrimSpace(spec[i:])
+	}
+
+	if strings.HasPrefix(spec, "@every") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every")))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCron, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("%w: @every interval must be at least 1s", ErrInvalidCron)
+		}
+		return &everySchedule{interval: interval}, nil
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := cronMacros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown macro %q", ErrInvalidCron, spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: expected 5 or 6 fields, got %d", ErrInvalidCron, len(fields))
+	}
+
+	s := &cronSchedule{location: location}
+	var err error
+	if s.second, _, err = parseCronField(fields[0], secondBounds); err != nil {
+		return nil, err
+	}
+	if s.minute, _, err = parseCronField(fields[1], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, _, err = parseCronField(fields[2], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dom, s.domStar, err = parseCronField(fields[3], domBounds); err != nil {
+		return nil, err
+	}
+	if s.month, _, err = parseCronField(fields[4], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dow, s.dowStar, err = parseCronField(fields[5], dowBounds); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	return s, nil
+}
+
+// parseCronField 解析单个字段, 返回位图以及该字段是否为完整通配
+func parseCronField(field string, b cronBounds) (uint64, bool, error) {
+	var bits uint64
+	star := false
+
+	for _, part := range strings.Split(field, ",") {
+		rangeAndStep := strings.Split(part, "/")
+		if len(rangeAndStep) > 2 {
+			return 0, false, fmt.Errorf("%w: too many slashes in %q", ErrInvalidCron, part)
+		}
+
+		var start, end uint
+		wildcard := false
+		lowAndHigh := strings.Split(rangeAndStep[0], "-")
+		switch {
+		case lowAndHigh[0] == "*" || lowAndHigh[0] == "?":
+			if len(lowAndHigh) > 1 {
+				return 0, false, fmt.Errorf("%w: invalid range %q", ErrInvalidCron, part)
+			}
+			start, end, wildcard = b.min, b.max, true
+		case len(lowAndHigh) <= 2:
+			var err error
+			if start, err = parseCronValue(lowAndHigh[0], b); err != nil {
+				return 0, false, err
+			}
+			end = start
+			if len(lowAndHigh) == 2 {
+				if end, err = parseCronValue(lowAndHigh[1], b); err != nil {
+					return 0, false, err
+				}
+			}
+		default:
+			return 0, false, fmt.Errorf("%w: too many hyphens in %q", ErrInvalidCron, part)
+		}
+
+		step := uint(1)
+		if len(rangeAndStep) == 2 {
+			n, err := strconv.ParseUint(rangeAndStep[1], 10, 0)
+			if err != nil || n == 0 {
+				return 0, false, fmt.Errorf("%w: invalid step in %q", ErrInvalidCron, part)
+			}
+			step = uint(n)
+			// "N/step" 表示从 N 开始直到上界
+			if len(lowAndHigh) == 1 && !wildcard {
+				end = b.max
+			}
+		}
+
+		if start < b.min || end > b.max || start > end {
+			return 0, false, fmt.Errorf("%w: %q out of range [%d, %d]", ErrInvalidCron, part, b.min, b.max)
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+		if wildcard && step == 1 {
+			star = true
+		}
+	}
+
+	return bits, star, nil
+}
+
+func parseCronValue(s string, b cronBounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 0)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid value %q", ErrInvalidCron, s)
+	}
+	return uint(n), nil
+}
+
+func (s *everySchedule) Next(t time.Time) time.Time {
+	// time.Truncate 以 Go 零时间对齐, 这里改用 Unix 纪元
+	n, i := t.UnixNano(), int64(s.interval)
+	n -= (n%i + i) % i
+	return time.Unix(0, n+i).In(t.Location())
+}
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	origLocation := t.Location()
+	loc := origLocation
+	if s.location != nil {
+		loc = s.location
+	}
+	t = t.In(loc)
+
+	// 从下一秒开始查找
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	added := false
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// 夏令时切换可能导致跨天后不在零点, 修正回当天零点
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(-time.Duration(t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t.In(origLocation)
+}
+
+// dayMatches 遵循 Vixie cron 语义: 日与周均非通配时任一满足即可, 否则两者都需满足
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow > 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
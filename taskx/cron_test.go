@@ -0,0 +1,97 @@
+package taskx
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCronNext(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 30, 20, 0, time.UTC) // 周一
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * * *", time.Date(2024, 1, 15, 10, 30, 30, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * sat,sun", time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 1, 21, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"30 8-10/2 * * mon-fri", time.Date(2024, 1, 16, 8, 30, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 1m", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"@every 45s", time.Unix((base.Unix()/45+1)*45, 0).UTC()},
+		{"@every 7s", time.Unix((base.Unix()/7+1)*7, 0).UTC()},
+	}
+
+	for _, c := range cases {
+		schedule, err := ParseCron(c.spec)
+		if !assert.NoError(t, err, c.spec) {
+			continue
+		}
+		assert.Equal(t, c.want, schedule.Next(base), c.spec)
+	}
+}
+
+func TestParseCronTimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+
+	schedule, err := ParseCron("TZ=Asia/Shanghai 0 9 * * *")
+	assert.NoError(t, err)
+
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC) // 上海时间 08:00
+	next := schedule.Next(base)
+	assert.Equal(t, time.Date(2024, 1, 15, 9, 0, 0, 0, loc).Unix(), next.Unix())
+	assert.Equal(t, time.UTC, next.Location())
+
+	_, err = ParseCron("CRON_TZ=Asia/Shanghai @daily")
+	assert.NoError(t, err)
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"1-2-3 * * * *",
+		"@fortnightly",
+		"@every 10ms",
+		"@every soon",
+		"TZ=Mars/Olympus * * * * *",
+	}
+
+	for _, spec := range specs {
+		_, err := ParseCron(spec)
+		assert.True(t, errors.Is(err, ErrInvalidCron), spec)
+	}
+}
+
+func TestScheduleTaskConfigValidate(t *testing.T) {
+	cfg := &ScheduleTaskConfig{BaseTaskConfig: BaseTaskConfig{ID: "report"}, Cron: "0 9 * * *"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Cron = "bad"
+	assert.Error(t, cfg.Validate())
+
+	cfg.ID = ""
+	assert.ErrorIs(t, cfg.Validate(), ErrInvalidConfig)
+}
@@ -0,0 +1,166 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AttemptRecord 单次执行结果的可序列化形式
+type AttemptRecord struct {
+	Attempt    int           `json:"attempt"`
+	Status     TaskStatus    `json:"status"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+	PanicError string        `json:"panic_error,omitempty"`
+	StackTrace string        `json:"stack_trace,omitempty"`
+}
+
+func newAttemptRecord(result *TaskResult) AttemptRecord {
+	record := AttemptRecord{
+		Attempt:    result.Attempt,
+		Status:     result.Status,
+		StartTime:  result.StartTime,
+		EndTime:    result.EndTime,
+		Duration:   result.Duration,
+		StackTrace: string(result.StackTrace),
+	}
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	if result.PanicError != nil {
+		record.PanicError = fmt.Sprint(result.PanicError)
+	}
+	return record
+}
+
+// DeadLetter 重试耗尽后的任务, Job.Failures 保存了每次失败的记录
+type DeadLetter struct {
+	ID         string        `json:"id"`
+	Job        Job           `json:"job"`
+	LastResult AttemptRecord `json:"last_result"`
+	DiedAt     time.Time     `json:"died_at"`
+}
+
+// deadLetter 把重试耗尽的任务写入死信队列, 超出容量时淘汰最早的死信
+func (tm *TaskManager) deadLetter(ctx context.Context, job *Job, result *TaskResult) error {
+	dl := &DeadLetter{
+		ID:         uuid.New().String(),
+		Job:        *job,
+		LastResult: newAttemptRecord(result),
+		DiedAt:     time.Now(),
+	}
+	dl.Job.Failures = appendFailure(job.Failures, result)
+
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+
+	return tm.broker.ArchiveAdd(ctx, tm.keyManager.TaskDeadLetterKey(),
+		dl.ID, string(data), dl.DiedAt, defaultDeadLetterMax)
+}
+
+// appendFailure 追加一次失败记录, 历史记录不保留堆栈以控制体积
+func appendFailure(failures []AttemptRecord, result *TaskResult) []AttemptRecord {
+	record := newAttemptRecord(result)
+	record.StackTrace = ""
+	return append(append([]AttemptRecord(nil), failures...), record)
+}
+
+// CountDeadLetters 返回死信数量
+func (tm *TaskManager) CountDeadLetters(ctx context.Context) (int64, error) {
+	return tm.broker.ArchiveLen(ctx, tm.keyManager.TaskDeadLetterKey())
+}
+
+// ListDeadLetters 按死亡时间倒序分页列出死信
+func (tm *TaskManager) ListDeadLetters(ctx context.Context, offset, limit int) ([]*DeadLetter, error) {
+	records, err := tm.broker.ArchiveList(ctx, tm.keyManager.TaskDeadLetterKey(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*DeadLetter, 0, len(records))
+	for _, data := range records {
+		dl := &DeadLetter{}
+		if err := json.Unmarshal([]byte(data), dl); err != nil {
+			continue
+		}
+		letters = append(letters, dl)
+	}
+	return letters, nil
+}
+
+// GetDeadLetter 查看单条死信
+func (tm *TaskManager) GetDeadLetter(ctx context.Context, id string) (*DeadLetter, error) {
+	data, err := tm.broker.ArchiveGet(ctx, tm.keyManager.TaskDeadLetterKey(), id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dl := &DeadLetter{}
+	if err := json.Unmarshal([]byte(data), dl); err != nil {
+		return nil, err
+	}
+	return dl, nil
+}
+
+// RequeueDeadLetter 把死信重新投递到派发队列, 重试计数与失败历史会被重置
+func (tm *TaskManager) RequeueDeadLetter(ctx context.Context, id string) (*Job, error) {
+	dl, err := tm.GetDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job := dl.Job
+	job.Retried = 0
+	job.Failures = nil
+	job.EnqueuedAt = time.Now()
+	job.ExecuteAt = job.EnqueuedAt
+
+	data, err := json.Marshal(&job)
+	if err != nil {
+		return nil, err
+	}
+	letter, err := json.Marshal(dl)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先移除死信, 保证并发重投时只有一方成功; 投递失败时放回死信队列
+	removed, err := tm.broker.ArchiveRemove(ctx, tm.keyManager.TaskDeadLetterKey(), id)
+	if err != nil {
+		return nil, err
+	}
+	if removed == 0 {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	_, err = tm.broker.EnqueueJob(ctx, &EnqueueArgs{
+		JobID:   job.ID,
+		JobKey:  tm.keyManager.TaskJobKey(job.ID),
+		JobData: string(data),
+		Queue:   tm.keyManager.QueueKey(tm.jobQueue(&job)),
+	})
+	if err != nil {
+		tm.broker.ArchiveAdd(ctx, tm.keyManager.TaskDeadLetterKey(), dl.ID, string(letter), dl.DiedAt, defaultDeadLetterMax)
+		return nil, err
+	}
+
+	tm.setStatus(ctx, &job, TaskStatusPending, "", nil)
+	return &job, nil
+}
+
+// PurgeDeadLetters 删除指定死信, 不传 ids 时清空整个死信队列
+func (tm *TaskManager) PurgeDeadLetters(ctx context.Context, ids ...string) (int64, error) {
+	return tm.broker.ArchiveRemove(ctx, tm.keyManager.TaskDeadLetterKey(), ids...)
+}
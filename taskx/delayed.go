@@ -0,0 +1,104 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// promoter 周期性地把延迟集合中到期的任务移入派发队列, 只在 Leader 上执行
+func (tm *TaskManager) promoter() {
+	ticker := time.NewTicker(time.Second * defaultPromoteInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tm.ctx.Done():
+			return
+		case <-ticker.C:
+			if tm.IsLeader() {
+				tm.promoteDue(time.Now())
+			}
+		}
+	}
+}
+
+func (tm *TaskManager) promoteDue(now time.Time) {
+	for _, q := range tm.queues {
+		for {
+			n, err := tm.broker.PromoteDue(tm.ctx, tm.keyManager.QueueDelayedKey(q.Name),
+				tm.keyManager.QueueKey(q.Name), now, defaultPromoteBatch)
+			if err != nil || n < defaultPromoteBatch {
+				break
+			}
+		}
+	}
+}
+
+// scheduleAt 把任务放入所属队列的延迟集合, 到期后由 promoter 投递; 已在集合中时保留原有时间
+func (tm *TaskManager) scheduleAt(ctx context.Context, task Task, at time.Time) error {
+	tm.markDue(ctx, task.GetID(), at)
+	return tm.broker.ScheduleIfAbsent(ctx, tm.keyManager.QueueDelayedKey(tm.taskQueue(task)), task.GetID(), at)
+}
+
+// markDue 记录系统调度任务的到期时间, 裸任务ID没有任务记录, 派发时据此统计派发延迟
+func (tm *TaskManager) markDue(ctx context.Context, taskID string, at time.Time) {
+	tm.broker.Set(ctx, tm.keyManager.TaskDueKey(taskID), strconv.FormatInt(at.UnixMilli(), 10), tm.statusTTL)
+}
+
+// dueAt 读取系统调度任务的到期时间, 没有记录时返回零值
+func (tm *TaskManager) dueAt(ctx context.Context, taskID string) time.Time {
+	value, err := tm.broker.Get(ctx, tm.keyManager.TaskDueKey(taskID))
+	if err != nil {
+		return time.Time{}
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// armTask 为单次任务与持续任务安排首次执行
+func (tm *TaskManager) armTask(ctx context.Context, task Task) error {
+	switch cfg := task.GetConfig().(type) {
+	case *OnceTaskConfig:
+		return tm.scheduleOnce(ctx, task, cfg.ExecuteAt)
+	case *ContinuousTaskConfig:
+		return tm.scheduleAt(ctx, task, time.Now())
+	}
+	return nil
+}
+
+// scheduleOnce 安排单次任务, 执行时间未变化时不重复安排
+// 多个实例并发注册时可能同时写入, 但延迟集合中的成员相同, 不会导致重复执行
+func (tm *TaskManager) scheduleOnce(ctx context.Context, task Task, at time.Time) error {
+	onceKey := tm.keyManager.TaskOnceKey(task.GetID())
+	marker := strconv.FormatInt(at.UnixMilli(), 10)
+
+	current, err := tm.broker.Get(ctx, onceKey)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	if current == marker {
+		return nil
+	}
+
+	// 安排成功后再写入标记, 安排失败时重新注册仍会重试
+	if err := tm.broker.Schedule(ctx, tm.keyManager.QueueDelayedKey(tm.taskQueue(task)), task.GetID(), at); err != nil {
+		return err
+	}
+	tm.markDue(ctx, task.GetID(), at)
+	return tm.broker.Set(ctx, onceKey, marker, 0)
+}
+
+// rearmContinuous 持续任务每次执行结束后间隔 Interval 再次入队
+// 到期任务由 promoter 每秒提升一次, 因此实际间隔的精度为秒级
+func (tm *TaskManager) rearmContinuous(ctx context.Context, task Task) {
+	cfg, ok := task.GetConfig().(*ContinuousTaskConfig)
+	if !ok {
+		return
+	}
+	tm.scheduleAt(ctx, task, time.Now().Add(cfg.Interval))
+}
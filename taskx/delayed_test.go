@@ -0,0 +1,116 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// configTask 使用任意任务配置的测试任务
+type configTask struct {
+	typ     TaskType
+	cfg     TaskConfig
+	execute func(ctx context.Context) error
+}
+
+func (t *configTask) Execute(ctx context.Context) error { return t.execute(ctx) }
+func (t *configTask) GetID() string                     { return baseConfigOf(t.cfg).ID }
+func (t *configTask) GetType() TaskType                 { return t.typ }
+func (t *configTask) GetConfig() TaskConfig             { return t.cfg }
+
+func TestTaskManagerOnceTask(t *testing.T) {
+	tm := newTestManager(t)
+
+	var runs int32
+	task := &configTask{
+		typ: TaskTypeOnce,
+		cfg: &OnceTaskConfig{
+			BaseTaskConfig: BaseTaskConfig{ID: "once"},
+			ExecuteAt:      time.Now().Add(500 * time.Millisecond),
+		},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}
+	assert.NoError(t, tm.RegisterTask(task))
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		return atomic.LoadInt32(&runs) == 1
+	})
+
+	// 以相同的执行时间重复注册不会再次执行
+	assert.NoError(t, tm.RegisterTask(task))
+	time.Sleep(2500 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+}
+
+func TestTaskManagerOnceTaskScheduleError(t *testing.T) {
+	broker := newFaultyBroker()
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(tm.Stop)
+	ctx := context.Background()
+
+	var runs int32
+	task := &configTask{
+		typ: TaskTypeOnce,
+		cfg: &OnceTaskConfig{BaseTaskConfig: BaseTaskConfig{ID: "once"}},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}
+	broker.failNext("Schedule", tm.keyManager.QueueDelayedKey(DefaultQueue), 1)
+	assert.ErrorIs(t, tm.RegisterTask(task), errInjected)
+
+	// 安排失败时不写入标记, 重新注册仍会安排
+	_, err := broker.Get(ctx, tm.keyManager.TaskOnceKey("once"))
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+	assert.NoError(t, tm.RegisterTask(task))
+	n, err := broker.ScheduledLen(ctx, tm.keyManager.QueueDelayedKey(DefaultQueue))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		return atomic.LoadInt32(&runs) == 1
+	})
+}
+
+func TestTaskManagerContinuousTask(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(&configTask{
+		typ: TaskTypeContinuous,
+		cfg: &ContinuousTaskConfig{
+			BaseTaskConfig: BaseTaskConfig{ID: "poll", RetryCount: -1},
+			Interval:       time.Second,
+		},
+		execute: func(ctx context.Context) error {
+			// 失败后同样按间隔再次执行
+			if atomic.AddInt32(&runs, 1)%2 == 1 {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}))
+
+	start := time.Now()
+	tm.Start()
+	waitFor(t, 10*time.Second, func() bool {
+		return atomic.LoadInt32(&runs) >= 3
+	})
+	// 首次立即执行, 之后每次间隔至少 Interval
+	assert.GreaterOrEqual(t, time.Since(start), 2*time.Second)
+
+	n, err := tm.broker.ScheduledLen(ctx, tm.keyManager.QueueDelayedKey(DefaultQueue))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, n, int64(1))
+}
@@ -0,0 +1,125 @@
+package taskx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// blockedMove 一次阻塞等待的结果, entry 为空表示超时或出错
+type blockedMove struct {
+	queue  string
+	worker *Worker
+	entry  string
+}
+
+// blockingDispatcher 阻塞派发, 代替每秒一次的轮询
+// 每个队列由一个协程在队列上阻塞等待, 新任务入队后立即被原子地移入预留了名额的 Worker 处理列表, 再由本协程派发;
+// 每次被唤醒后还会做一次非阻塞派发以处理积压, 阻塞超时(dispatchInterval)即兜底轮询
+// 只派发给本实例的 Worker, 不再逐个读取心跳
+func (tm *TaskManager) blockingDispatcher() {
+	moves := make(chan blockedMove)
+	waiting := make(map[string]bool, len(tm.queues))
+	// stalled 领取到本实例无法处理的条目的队列, 在下一次兜底轮询或 Worker 空出名额前不再阻塞等待,
+	// 避免立即重新领取刚放回的同一条目而空转
+	stalled := make(map[string]bool, len(tm.queues))
+	var waiters sync.WaitGroup
+
+	defer func() {
+		// 停止后阻塞中的命令返回时可能已领取任务, 在后台放回队列, 不拖延关闭
+		go func() {
+			waiters.Wait()
+			close(moves)
+		}()
+		go func() {
+			defer close(tm.drained)
+			for m := range moves {
+				if m.entry != "" {
+					tm.returnEntry(m)
+				}
+			}
+		}()
+	}()
+
+	ticker := time.NewTicker(tm.dispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		paused := tm.loadPauseState()
+		workers := tm.workerList()
+		tm.dispatchTasks(workers, paused)
+
+		for _, q := range tm.queues {
+			if waiting[q.Name] || stalled[q.Name] || paused.queuePaused(q.Name) {
+				continue
+			}
+			worker := pickWorker(workers, q.Name)
+			if worker == nil {
+				// 没有空闲名额, 等待 Worker 空出名额后再阻塞
+				continue
+			}
+
+			worker.reserved++
+			waiting[q.Name] = true
+			waiters.Add(1)
+			go func(queue string, worker *Worker) {
+				defer waiters.Done()
+				entry, _ := tm.broker.BlockingMove(tm.ctx, tm.keyManager.QueueKey(queue),
+					tm.keyManager.QueueProcessingKey(worker.id, queue), tm.dispatchInterval)
+				moves <- blockedMove{queue: queue, worker: worker, entry: entry}
+			}(q.Name, worker)
+		}
+
+		select {
+		case <-tm.ctx.Done():
+			return
+		case m := <-moves:
+			waiting[m.queue] = false
+			m.worker.reserved--
+			if m.entry != "" && !tm.deliver(m.worker, m.queue, m.entry, paused) {
+				stalled[m.queue] = true
+			}
+		case <-tm.freed:
+			resetStalled(stalled)
+		case <-ticker.C:
+			resetStalled(stalled)
+		}
+	}
+}
+
+func resetStalled(stalled map[string]bool) {
+	for queue := range stalled {
+		delete(stalled, queue)
+	}
+}
+
+// pickWorker 选择会处理该队列且空闲名额最多的 Worker
+func pickWorker(workers []*Worker, queue string) *Worker {
+	var best *Worker
+	bestFree := 0
+	for _, w := range workers {
+		if !w.selector.has(queue) {
+			continue
+		}
+		if free := w.freeSlots(); free > bestFree {
+			best, bestFree = w, free
+		}
+	}
+	return best
+}
+
+// returnEntry 把停止后才领取到的条目放回队列
+func (tm *TaskManager) returnEntry(m blockedMove) {
+	ctx := context.Background()
+	handOff(ctx, tm.broker, tm.keyManager.QueueProcessingKey(m.worker.id, m.queue), m.entry, func() error {
+		return tm.broker.Push(ctx, tm.keyManager.QueueKey(m.queue), m.entry)
+	})
+}
+
+// notifyFreed 通知调度协程有 Worker 空出名额
+func (tm *TaskManager) notifyFreed() {
+	select {
+	case tm.freed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,212 @@
+package taskx
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBrokerBlockingMove(t *testing.T) {
+	b := NewMemoryBroker()
+	ctx := context.Background()
+
+	_, err := b.BlockingMove(ctx, "q", "p", 20*time.Millisecond)
+	assert.ErrorIs(t, err, ErrQueueEmpty)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Push(ctx, "q", "a")
+	}()
+	id, err := b.BlockingMove(ctx, "q", "p", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", id)
+	moved, _ := b.Peek(ctx, "p", 10)
+	assert.Equal(t, []string{"a"}, moved)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = b.BlockingMove(cancelled, "q", "p", time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestTaskManagerBlockingDispatch(t *testing.T) {
+	// 兜底轮询间隔足够长, 任务只能由阻塞等待唤醒
+	tm := newTestManager(t, WithBlockingDispatch(true), WithDispatchInterval(time.Minute),
+		WithQueues(Queue{Name: "mail", Priority: 2}))
+	ctx := context.Background()
+
+	done := make(chan string, 4)
+	for _, id := range []string{"report", "mail"} {
+		task := &testTask{BaseTaskConfig: BaseTaskConfig{ID: id}}
+		task.execute = func(ctx context.Context) error {
+			done <- task.ID
+			return nil
+		}
+		if id == "mail" {
+			task.Queue = "mail"
+		}
+		assert.NoError(t, tm.RegisterTask(task))
+	}
+
+	tm.Start()
+	time.Sleep(50 * time.Millisecond)
+	for _, id := range []string{"report", "mail"} {
+		_, err := tm.Enqueue(ctx, id)
+		assert.NoError(t, err)
+		select {
+		case got := <-done:
+			assert.Equal(t, id, got)
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not dispatched", id)
+		}
+	}
+}
+
+func TestBlockingDispatchUnresolvedEntry(t *testing.T) {
+	broker := &countingBroker{Broker: NewMemoryBroker()}
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1), WithBlockingDispatch(true))
+	defer tm.Stop()
+	ctx := context.Background()
+
+	done := make(chan struct{}, 1)
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "noop"},
+		execute: func(ctx context.Context) error {
+			done <- struct{}{}
+			return nil
+		},
+	}))
+	// 只在其他实例注册的任务, 本实例领取后放回队列
+	queueKey := tm.keyManager.QueueKey(DefaultQueue)
+	assert.NoError(t, broker.Push(ctx, queueKey, "remote"))
+	tm.Start()
+
+	time.Sleep(100 * time.Millisecond)
+	before := atomic.LoadInt64(&broker.calls)
+	time.Sleep(time.Second)
+	// 不会立即重新领取放回的条目, 调用次数与轮询同一量级
+	assert.Less(t, atomic.LoadInt64(&broker.calls)-before, int64(50))
+
+	// 其他任务仍能派发, 无法处理的条目留在队列中
+	_, err := tm.Enqueue(ctx, "noop")
+	assert.NoError(t, err)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("noop was not dispatched")
+	}
+	waitFor(t, 3*time.Second, func() bool {
+		queued, _ := broker.Peek(ctx, queueKey, 10)
+		return len(queued) == 1 && queued[0] == "remote"
+	})
+}
+
+// countingBroker 统计派发路径上的 Broker 调用次数
+type countingBroker struct {
+	Broker
+	calls int64
+}
+
+func (b *countingBroker) Get(ctx context.Context, key string) (string, error) {
+	atomic.AddInt64(&b.calls, 1)
+	return b.Broker.Get(ctx, key)
+}
+
+func (b *countingBroker) Move(ctx context.Context, queue, processing string) (string, error) {
+	atomic.AddInt64(&b.calls, 1)
+	return b.Broker.Move(ctx, queue, processing)
+}
+
+func (b *countingBroker) BlockingMove(ctx context.Context, queue, processing string, timeout time.Duration) (string, error) {
+	atomic.AddInt64(&b.calls, 1)
+	return b.Broker.BlockingMove(ctx, queue, processing, timeout)
+}
+
+// benchmarkDispatch 测量任务从入队到开始执行的延迟, 以及空闲一秒内的 Broker 调用次数
+// unresolved 时队列中另有一个本实例未注册的条目, 会被反复领取并放回
+func benchmarkDispatch(b *testing.B, blocking, unresolved bool) {
+	broker := &countingBroker{Broker: NewMemoryBroker()}
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(4), WithBlockingDispatch(blocking))
+	defer tm.Stop()
+
+	started := make(chan struct{}, 1)
+	tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "noop"},
+		execute: func(ctx context.Context) error {
+			started <- struct{}{}
+			return nil
+		},
+	})
+	ctx := context.Background()
+	if unresolved {
+		broker.Push(ctx, tm.keyManager.QueueKey(DefaultQueue), "remote")
+	}
+	tm.Start()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tm.Enqueue(ctx, "noop"); err != nil {
+			b.Fatal(err)
+		}
+		<-started
+	}
+	b.StopTimer()
+
+	before := atomic.LoadInt64(&broker.calls)
+	time.Sleep(time.Second)
+	b.ReportMetric(float64(atomic.LoadInt64(&broker.calls)-before), "idle-calls/s")
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	for _, blocking := range []bool{false, true} {
+		for _, unresolved := range []bool{false, true} {
+			b.Run(fmt.Sprintf("blocking=%v/unresolved=%v", blocking, unresolved), func(b *testing.B) {
+				benchmarkDispatch(b, blocking, unresolved)
+			})
+		}
+	}
+}
+
+// stuckBroker 模拟 Redis 的 BRPOPLPUSH, 阻塞期间不响应 ctx 取消
+type stuckBroker struct {
+	Broker
+}
+
+func (b *stuckBroker) BlockingMove(ctx context.Context, queue, processing string, timeout time.Duration) (string, error) {
+	time.Sleep(timeout)
+	return b.Broker.Move(ctx, queue, processing)
+}
+
+func TestShutdownBlockingDispatchDeadline(t *testing.T) {
+	broker := &stuckBroker{Broker: NewMemoryBroker()}
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1),
+		WithBlockingDispatch(true), WithDispatchInterval(1500*time.Millisecond))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "noop"},
+		execute:        func(ctx context.Context) error { return nil },
+	}))
+	tm.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.NoError(t, tm.Shutdown(ctx))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// 关闭后阻塞命令领取到的任务被放回队列
+	bg := context.Background()
+	assert.NoError(t, broker.Push(bg, tm.keyManager.QueueKey(DefaultQueue), "noop"))
+	select {
+	case <-tm.drained:
+	case <-time.After(3 * time.Second):
+		t.Fatal("blocking move did not return")
+	}
+	queued, err := broker.Peek(bg, tm.keyManager.QueueKey(DefaultQueue), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"noop"}, queued)
+}
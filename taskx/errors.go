@@ -3,10 +3,27 @@ package taskx
 import "errors"
 
 var (
-	ErrTaskNotFound   = errors.New("task not found")
-	ErrTaskLockFailed = errors.New("failed to acquire task lock")
-	ErrTaskTimeout    = errors.New("task execution timeout")
-	ErrInvalidConfig  = errors.New("invalid task configuration")
-	ErrWorkerStopped  = errors.New("worker has been stopped")
-	ErrManagerStopped = errors.New("task manager has been stopped")
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrTaskLockFailed     = errors.New("failed to acquire task lock")
+	ErrTaskTimeout        = errors.New("task execution timeout")
+	ErrInvalidConfig      = errors.New("invalid task configuration")
+	ErrWorkerStopped      = errors.New("worker has been stopped")
+	ErrManagerStopped     = errors.New("task manager has been stopped")
+	ErrInvalidCron        = errors.New("invalid cron expression")
+	ErrDuplicateJob       = errors.New("duplicate job")
+	ErrKeyNotFound        = errors.New("key not found")
+	ErrQueueEmpty         = errors.New("queue is empty")
+	ErrJobNotFound        = errors.New("job not found")
+	ErrInvalidJob         = errors.New("invalid job record")
+	ErrShutdownTimeout    = errors.New("shutdown timed out")
+	ErrQueueNotFound      = errors.New("queue not found")
+	ErrConcurrencyLimited = errors.New("concurrency limit reached")
+	ErrJobCancelled       = errors.New("job cancelled")
+	ErrJobFinished        = errors.New("job already finished")
+
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+	ErrInvalidWorkflow   = errors.New("invalid workflow")
+	ErrWorkflowNotFound  = errors.New("workflow not found")
+	ErrDuplicateWorkflow = errors.New("duplicate workflow")
 )
@@ -1,5 +1,7 @@
 package taskx
 
+import "time"
+
 // TaskHook 定义任务生命周期钩子
 type TaskHook interface {
 	OnTaskStart(task Task) error
@@ -8,6 +10,21 @@ type TaskHook interface {
 	OnTaskPanic(task Task, result *TaskResult) error
 }
 
+// RetryHook 可选钩子, TaskHook 同时实现该接口时在任务安排重试后触发
+type RetryHook interface {
+	OnTaskRetry(task Task, result *TaskResult, delay time.Duration) error
+}
+
+// ProgressHook 可选钩子, TaskHook 同时实现该接口时在任务上报进度后触发
+type ProgressHook interface {
+	OnTaskProgress(task Task, progress *Progress) error
+}
+
+// ScaleHook 可选钩子, TaskHook 同时实现该接口时在自动伸缩后触发
+type ScaleHook interface {
+	OnScale(event *ScaleEvent) error
+}
+
 // NoopTaskHook 提供空实现
 type NoopTaskHook struct{}
 
@@ -15,3 +32,8 @@ func (h *NoopTaskHook) OnTaskStart(task Task) error                        { ret
 func (h *NoopTaskHook) OnTaskComplete(task Task, result *TaskResult) error { return nil }
 func (h *NoopTaskHook) OnTaskFail(task Task, result *TaskResult) error     { return nil }
 func (h *NoopTaskHook) OnTaskPanic(task Task, result *TaskResult) error    { return nil }
+func (h *NoopTaskHook) OnTaskRetry(task Task, result *TaskResult, delay time.Duration) error {
+	return nil
+}
+func (h *NoopTaskHook) OnTaskProgress(task Task, progress *Progress) error { return nil }
+func (h *NoopTaskHook) OnScale(event *ScaleEvent) error                    { return nil }
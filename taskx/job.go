@@ -0,0 +1,220 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job 表示一次入队的任务执行, 由 Enqueue 系列方法返回
+type Job struct {
+	ID         string    `json:"id"`
+	TaskID     string    `json:"task_id"`
+	Queue      string    `json:"queue,omitempty"`
+	DedupKey   string    `json:"dedup_key,omitempty"`
+	UniqueKey  string    `json:"unique_key,omitempty"`
+	Retried    int       `json:"retried"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	ExecuteAt  time.Time `json:"execute_at"`
+	Payload    []byte    `json:"payload,omitempty"`
+	// Trace 投递方的链路上下文, 由 Tracer 写入与恢复
+	Trace map[string]string `json:"trace,omitempty"`
+	// Workflow 与 Node 标识任务所属的工作流节点
+	Workflow string `json:"workflow,omitempty"`
+	Node     string `json:"node,omitempty"`
+	// Failures 历次失败记录
+	Failures []AttemptRecord `json:"failures,omitempty"`
+	// Duplicate 为 true 表示命中唯一键被合并, 返回的是已存在的任务
+	Duplicate bool `json:"-"`
+}
+
+// delivery 是派发给 Worker 的执行单元
+type delivery struct {
+	task Task
+	job  *Job
+}
+
+type EnqueueOptions struct {
+	// DedupKey 非空时, 同一去重键在任务结束前只允许存在一个任务
+	DedupKey string
+	// DedupTTL 去重键的最长保留时间, 防止异常情况下去重键永不释放
+	DedupTTL time.Duration
+	// Payload 任务参数, 由 TaskManager 的编解码器编码
+	Payload interface{}
+	// RawPayload 已编码的任务参数, 优先于 Payload
+	RawPayload []byte
+	// Queue 投递的队列, 为空时使用任务配置的队列
+	Queue string
+	// UniqueKey 非空时, 同一唯一键在 UniqueTTL 时间窗口内只会入队一次, 任务结束后也不会释放
+	UniqueKey  string
+	UniqueTTL  time.Duration
+	UniqueMode UniqueMode
+
+	workflow string
+	node     string
+}
+
+type EnqueueOption func(*EnqueueOptions)
+
+func WithDedupKey(key string) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.DedupKey = key
+	}
+}
+
+func WithDedupTTL(ttl time.Duration) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.DedupTTL = ttl
+	}
+}
+
+func WithQueue(queue string) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.Queue = queue
+	}
+}
+
+// Enqueue 将已注册的任务立即投递到派发队列
+func (tm *TaskManager) Enqueue(ctx context.Context, taskID string, opts ...EnqueueOption) (*Job, error) {
+	return tm.EnqueueAt(ctx, taskID, time.Time{}, opts...)
+}
+
+// EnqueueIn 在 delay 之后投递任务
+func (tm *TaskManager) EnqueueIn(ctx context.Context, taskID string, delay time.Duration, opts ...EnqueueOption) (*Job, error) {
+	return tm.EnqueueAt(ctx, taskID, time.Now().Add(delay), opts...)
+}
+
+// EnqueueAt 在指定时间投递任务, at 不晚于当前时间时立即投递
+func (tm *TaskManager) EnqueueAt(ctx context.Context, taskID string, at time.Time, opts ...EnqueueOption) (*Job, error) {
+	if tm.ctx.Err() != nil {
+		return nil, ErrManagerStopped
+	}
+	return tm.enqueueAt(ctx, taskID, at, opts...)
+}
+
+// enqueueAt 投递任务, 关闭期间仍可由正在执行的任务(如工作流推进)调用
+func (tm *TaskManager) enqueueAt(ctx context.Context, taskID string, at time.Time, opts ...EnqueueOption) (*Job, error) {
+	tm.mu.RLock()
+	task, exists := tm.tasks[taskID]
+	tm.mu.RUnlock()
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+
+	options := EnqueueOptions{DedupTTL: time.Second * defaultDedupTTL}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.DedupTTL <= 0 {
+		options.DedupTTL = time.Second * defaultDedupTTL
+	}
+	if options.UniqueKey != "" && options.UniqueTTL <= 0 {
+		return nil, ErrInvalidConfig
+	}
+
+	queue := options.Queue
+	if queue == "" {
+		queue = tm.taskQueue(task)
+	}
+	if !tm.hasQueue(queue) {
+		return nil, ErrQueueNotFound
+	}
+
+	payload := options.RawPayload
+	if payload == nil && options.Payload != nil {
+		data, err := tm.codec.Marshal(options.Payload)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:         uuid.New().String(),
+		TaskID:     taskID,
+		Queue:      queue,
+		DedupKey:   options.DedupKey,
+		UniqueKey:  options.UniqueKey,
+		EnqueuedAt: now,
+		ExecuteAt:  now,
+		Payload:    payload,
+		Workflow:   options.workflow,
+		Node:       options.node,
+	}
+	if at.After(now) {
+		job.ExecuteAt = at
+	}
+	tm.injectTrace(ctx, job)
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	args := &EnqueueArgs{
+		JobID:    job.ID,
+		JobKey:   tm.keyManager.TaskJobKey(job.ID),
+		JobData:  string(data),
+		Queue:    tm.keyManager.QueueKey(queue),
+		DedupTTL: options.DedupTTL,
+	}
+	if job.ExecuteAt.After(now) {
+		args.Queue = tm.keyManager.QueueDelayedKey(queue)
+		args.At = job.ExecuteAt
+	}
+	if job.DedupKey != "" {
+		args.DedupKey = tm.keyManager.TaskDedupKey(job.DedupKey)
+	}
+	if job.UniqueKey != "" {
+		args.UniqueKey = tm.keyManager.TaskUniqueKey(job.UniqueKey)
+		args.UniqueTTL = options.UniqueTTL
+	}
+
+	added, err := tm.broker.EnqueueJob(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return tm.resolveDuplicate(ctx, job, &options)
+	}
+
+	tm.setStatus(ctx, job, TaskStatusPending, "", nil)
+	return job, nil
+}
+
+// loadJob 读取队列条目对应的任务记录, 记录无法解析时返回 ErrInvalidJob
+// 直接写入队列的裸任务ID没有记录, 视为以任务ID作为 JobID 的任务
+func (tm *TaskManager) loadJob(ctx context.Context, entry string) (*Job, error) {
+	data, err := tm.broker.Get(ctx, tm.keyManager.TaskJobKey(entry))
+	if errors.Is(err, ErrKeyNotFound) {
+		return &Job{ID: entry, TaskID: entry}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal([]byte(data), job); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+	return job, nil
+}
+
+// isJobID 判断条目是否为 Enqueue 生成的 JobID, 用于区分裸任务ID与记录已被清理的任务
+func isJobID(entry string) bool {
+	_, err := uuid.Parse(entry)
+	return err == nil && len(entry) == 36
+}
+
+// finishJob 在任务执行结束后清理任务记录并释放去重键
+func (tm *TaskManager) finishJob(ctx context.Context, job *Job) {
+	tm.broker.Del(ctx, tm.keyManager.TaskJobKey(job.ID))
+	if job.DedupKey != "" {
+		tm.broker.CompareAndDelete(ctx, tm.keyManager.TaskDedupKey(job.DedupKey), job.ID)
+	}
+}
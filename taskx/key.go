@@ -1,6 +1,7 @@
 package taskx
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -32,6 +33,154 @@ func (km *KeyManager) TaskQueueKey() string {
 	return km.buildKey("queues", "tasks")
 }
 
+// QueueKey 具名队列的派发列表, 默认队列与 TaskQueueKey 相同
+func (km *KeyManager) QueueKey(queue string) string {
+	if queue == "" || queue == DefaultQueue {
+		return km.TaskQueueKey()
+	}
+	return km.buildKey("queues", "tasks", queue)
+}
+
+// TaskProcessingKey Worker 已领取但尚未确认的任务列表
+func (km *KeyManager) TaskProcessingKey(workerID string) string {
+	return km.buildKey("queues", "processing", workerID)
+}
+
+// QueueProcessingKey Worker 从具名队列领取的任务列表, 默认队列与 TaskProcessingKey 相同
+func (km *KeyManager) QueueProcessingKey(workerID, queue string) string {
+	if queue == "" || queue == DefaultQueue {
+		return km.TaskProcessingKey(workerID)
+	}
+	return km.buildKey("queues", "processing", workerID, queue)
+}
+
+// WorkerRegistryKey 集群内所有 Worker 的注册表, score 为最近一次心跳时间(毫秒)
+func (km *KeyManager) WorkerRegistryKey() string {
+	return km.buildKey("workers", "registry")
+}
+
+// TaskDelayedKey 延迟任务有序集合, score 为到期时间(毫秒)
+func (km *KeyManager) TaskDelayedKey() string {
+	return km.buildKey("queues", "delayed")
+}
+
+// QueueDelayedKey 具名队列的延迟集合, 默认队列与 TaskDelayedKey 相同
+func (km *KeyManager) QueueDelayedKey(queue string) string {
+	if queue == "" || queue == DefaultQueue {
+		return km.TaskDelayedKey()
+	}
+	return km.buildKey("queues", "delayed", queue)
+}
+
 func (km *KeyManager) TaskStatusKey(taskID string) string {
 	return km.buildKey("status", "tasks", taskID)
 }
+
+// TaskScheduleKey 用于多实例间争抢同一次定时触发
+func (km *KeyManager) TaskScheduleKey(taskID string, fireAt int64) string {
+	return km.buildKey("schedules", "tasks", taskID, strconv.FormatInt(fireAt, 10))
+}
+
+// TaskOnceKey 记录单次任务已安排的执行时间, 避免重复注册导致重复执行
+func (km *KeyManager) TaskOnceKey(taskID string) string {
+	return km.buildKey("once", "tasks", taskID)
+}
+
+// TaskDueKey 系统调度任务最近一次的到期时间, 用于统计派发延迟
+func (km *KeyManager) TaskDueKey(taskID string) string {
+	return km.buildKey("due", "tasks", taskID)
+}
+
+// TaskJobKey 保存通过 Enqueue 投递的任务记录
+func (km *KeyManager) TaskJobKey(jobID string) string {
+	return km.buildKey("jobs", jobID)
+}
+
+func (km *KeyManager) TaskDedupKey(dedupKey string) string {
+	return km.buildKey("dedup", dedupKey)
+}
+
+// TaskUniqueKey 唯一任务的占用记录, 在有效期内始终保留, 不随任务结束释放
+func (km *KeyManager) TaskUniqueKey(uniqueKey string) string {
+	return km.buildKey("unique", uniqueKey)
+}
+
+// TaskRateLimitKey 任务级限流状态
+func (km *KeyManager) TaskRateLimitKey(taskID string) string {
+	return km.buildKey("ratelimit", "tasks", taskID)
+}
+
+// QueueRateLimitKey 队列级限流状态
+func (km *KeyManager) QueueRateLimitKey(queue string) string {
+	return km.buildKey("ratelimit", "queues", queue)
+}
+
+// TaskSemaphoreKey 任务级并发限制的信号量
+func (km *KeyManager) TaskSemaphoreKey(taskID string) string {
+	return km.buildKey("semaphores", "tasks", taskID)
+}
+
+// TagSemaphoreKey 标签级并发限制的信号量
+func (km *KeyManager) TagSemaphoreKey(tag string) string {
+	return km.buildKey("semaphores", "tags", tag)
+}
+
+// LeaderKey 选主租约, 同名的候选者竞争同一个领导权
+func (km *KeyManager) LeaderKey(name string) string {
+	return km.buildKey("leaders", name)
+}
+
+// WorkflowKey 工作流定义与进度
+func (km *KeyManager) WorkflowKey(id string) string {
+	return km.buildKey("workflows", id)
+}
+
+// WorkflowLockKey 串行化同一工作流的状态更新
+func (km *KeyManager) WorkflowLockKey(id string) string {
+	return km.buildKey("locks", "workflows", id)
+}
+
+// JobCancelKey 任务的取消标记
+func (km *KeyManager) JobCancelKey(jobID string) string {
+	return km.buildKey("cancels", jobID)
+}
+
+// JobCancelFinishKey 取消结果的记录权, 保证取消只被记录一次
+func (km *KeyManager) JobCancelFinishKey(jobID string) string {
+	return km.buildKey("cancels", "finished", jobID)
+}
+
+// CancelChannel 广播取消请求的 pub/sub 频道
+func (km *KeyManager) CancelChannel() string {
+	return km.buildKey("channels", "cancel")
+}
+
+// ProgressChannel 广播任务进度的 pub/sub 频道
+func (km *KeyManager) ProgressChannel() string {
+	return km.buildKey("channels", "progress")
+}
+
+// TaskDeadLetterKey 死信归档, 按死亡时间排序
+func (km *KeyManager) TaskDeadLetterKey() string {
+	return km.buildKey("queues", "dead")
+}
+
+// QueuePausedKey 队列的暂停标记
+func (km *KeyManager) QueuePausedKey(queue string) string {
+	return km.buildKey("paused", "queues", queue)
+}
+
+// TaskPausedKey 任务的暂停标记
+func (km *KeyManager) TaskPausedKey(taskID string) string {
+	return km.buildKey("paused", "tasks", taskID)
+}
+
+// TaskParkedKey 任务暂停期间从 queue 领取到的任务, 恢复时放回队列
+func (km *KeyManager) TaskParkedKey(taskID, queue string) string {
+	return km.buildKey("paused", "jobs", taskID, queue)
+}
+
+// TaskResultsKey 最近执行结果的归档
+func (km *KeyManager) TaskResultsKey() string {
+	return km.buildKey("results", "recent")
+}
@@ -0,0 +1,196 @@
+package taskx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LeaderElector 基于租约的分布式选主
+// 候选者周期性地争抢并续期租约, 持有租约期间为 Leader; 每次当选都会获得单调递增的 fencing token,
+// 下游可以用它拒绝来自旧 Leader 的过期写入
+type LeaderElector struct {
+	broker  Broker
+	key     string
+	options ElectionOptions
+
+	mu      sync.Mutex
+	leader  bool
+	token   int64
+	renewed time.Time
+	cancel  context.CancelFunc
+}
+
+type ElectionOptions struct {
+	// ID 候选者标识, 默认随机生成
+	ID string
+	// TTL 租约有效期, 每隔 TTL/3 续期一次
+	TTL time.Duration
+	// OnElected 当选时调用, ctx 在失去领导权时取消
+	OnElected func(ctx context.Context, token int64)
+	// OnRevoked 失去领导权时调用
+	OnRevoked func()
+}
+
+type ElectionOption func(*ElectionOptions)
+
+func WithCandidateID(id string) ElectionOption {
+	return func(o *ElectionOptions) {
+		o.ID = id
+	}
+}
+
+func WithLeaseTTL(ttl time.Duration) ElectionOption {
+	return func(o *ElectionOptions) {
+		o.TTL = ttl
+	}
+}
+
+func WithOnElected(fn func(ctx context.Context, token int64)) ElectionOption {
+	return func(o *ElectionOptions) {
+		o.OnElected = fn
+	}
+}
+
+func WithOnRevoked(fn func()) ElectionOption {
+	return func(o *ElectionOptions) {
+		o.OnRevoked = fn
+	}
+}
+
+// NewLeaderElector 创建选主器, 使用相同 key 的候选者之间竞争同一个领导权
+func NewLeaderElector(broker Broker, key string, opts ...ElectionOption) *LeaderElector {
+	options := ElectionOptions{
+		ID:  uuid.New().String(),
+		TTL: time.Second * defaultLeaderLease,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.TTL < 3*time.Millisecond {
+		options.TTL = time.Second * defaultLeaderLease
+	}
+
+	return &LeaderElector{
+		broker:  broker,
+		key:     key,
+		options: options,
+	}
+}
+
+// NewLeaderElector 在任务管理器的命名空间下创建选主器, 可用于业务自己的单例任务
+func (tm *TaskManager) NewLeaderElector(name string, opts ...ElectionOption) *LeaderElector {
+	return NewLeaderElector(tm.broker, tm.keyManager.LeaderKey(name), opts...)
+}
+
+// Run 参与选举直到 ctx 结束, 结束时主动释放租约以便其他候选者尽快接任
+func (e *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.options.TTL / 3)
+	defer ticker.Stop()
+
+	e.campaign(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return
+		case <-ticker.C:
+			e.campaign(ctx)
+		}
+	}
+}
+
+// IsLeader 返回当前是否持有领导权
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// FencingToken 返回本次任期的 fencing token, 非 Leader 时返回 0
+func (e *LeaderElector) FencingToken() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leader {
+		return 0
+	}
+	return e.token
+}
+
+// ID 返回候选者标识
+func (e *LeaderElector) ID() string {
+	return e.options.ID
+}
+
+// campaign 争抢或续期租约, 并根据结果切换领导状态
+// 回调在释放内部锁之后调用, 回调中可以安全地调用 IsLeader 等方法
+func (e *LeaderElector) campaign(ctx context.Context) {
+	token, err := e.broker.AcquireLease(ctx, e.key, e.options.ID, e.options.TTL)
+	now := time.Now()
+
+	var (
+		revoked   bool
+		elected   bool
+		leaderCtx context.Context
+	)
+
+	e.mu.Lock()
+	switch {
+	case err != nil:
+		// Broker 异常时无法确认租约, 超过有效期后主动退位
+		if e.leader && now.Sub(e.renewed) >= e.options.TTL {
+			revoked = e.stepDownLocked()
+		}
+	case token == 0:
+		revoked = e.stepDownLocked()
+	default:
+		e.renewed = now
+		if e.leader && e.token == token {
+			break
+		}
+		// 租约曾经过期后重新当选, 视为新的任期
+		revoked = e.stepDownLocked()
+
+		e.leader = true
+		e.token = token
+		leaderCtx, e.cancel = context.WithCancel(ctx)
+		elected = true
+	}
+	e.mu.Unlock()
+
+	if revoked && e.options.OnRevoked != nil {
+		e.options.OnRevoked()
+	}
+	if elected && e.options.OnElected != nil {
+		e.options.OnElected(leaderCtx, token)
+	}
+}
+
+// stepDownLocked 放弃领导权, 返回之前是否为 Leader
+func (e *LeaderElector) stepDownLocked() bool {
+	if !e.leader {
+		return false
+	}
+	e.leader = false
+	if e.cancel != nil {
+		e.cancel()
+		e.cancel = nil
+	}
+	return true
+}
+
+func (e *LeaderElector) resign() {
+	e.mu.Lock()
+	revoked := e.stepDownLocked()
+	e.mu.Unlock()
+
+	if !revoked {
+		return
+	}
+	e.broker.ReleaseLease(context.Background(), e.key, e.options.ID)
+	if e.options.OnRevoked != nil {
+		e.options.OnRevoked()
+	}
+}
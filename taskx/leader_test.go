@@ -0,0 +1,56 @@
+package taskx
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderElector(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+
+	var elected, revoked int32
+	first := NewLeaderElector(broker, "leader", WithCandidateID("a"), WithLeaseTTL(60*time.Millisecond),
+		WithOnElected(func(ctx context.Context, token int64) {
+			atomic.AddInt32(&elected, 1)
+		}),
+		WithOnRevoked(func() {
+			atomic.AddInt32(&revoked, 1)
+		}),
+	)
+	second := NewLeaderElector(broker, "leader", WithCandidateID("b"), WithLeaseTTL(60*time.Millisecond))
+
+	firstCtx, stopFirst := context.WithCancel(ctx)
+	firstDone := make(chan struct{})
+	go func() {
+		first.Run(firstCtx)
+		close(firstDone)
+	}()
+	waitFor(t, time.Second, first.IsLeader)
+
+	secondCtx, stopSecond := context.WithCancel(ctx)
+	defer stopSecond()
+	go second.Run(secondCtx)
+
+	// 续期期间领导权不会转移
+	time.Sleep(150 * time.Millisecond)
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+	token := first.FencingToken()
+	assert.Equal(t, int64(1), token)
+
+	// 主动退出后由其他候选者接任, fencing token 递增
+	stopFirst()
+	<-firstDone
+	assert.False(t, first.IsLeader())
+	assert.Equal(t, int64(0), first.FencingToken())
+	waitFor(t, time.Second, second.IsLeader)
+	assert.Greater(t, second.FencingToken(), token)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&elected))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&revoked))
+}
@@ -0,0 +1,82 @@
+package taskx
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// taskLock 基于随机令牌的分布式锁, 只有持有者可以续期与释放
+type taskLock struct {
+	tm    *TaskManager
+	key   string
+	token string
+	ttl   time.Duration
+}
+
+// acquireLock 获取锁, 锁已被占用时返回 ErrTaskLockFailed
+func (tm *TaskManager) acquireLock(ctx context.Context, key string) (*taskLock, error) {
+	lock := &taskLock{
+		tm:    tm,
+		key:   key,
+		token: uuid.New().String(),
+		ttl:   tm.lockTTL,
+	}
+
+	ok, err := tm.broker.SetNX(ctx, key, lock.token, lock.ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTaskLockFailed
+	}
+	return lock, nil
+}
+
+// renew 延长锁的有效期, 锁已不属于自己时返回 false
+func (l *taskLock) renew(ctx context.Context) (bool, error) {
+	return l.tm.broker.CompareAndExpire(ctx, l.key, l.token, l.ttl)
+}
+
+// release 仅在锁仍属于自己时删除
+func (l *taskLock) release(ctx context.Context) error {
+	_, err := l.tm.broker.CompareAndDelete(ctx, l.key, l.token)
+	return err
+}
+
+// keepAlive 启动看门狗, 每隔 TTL 的三分之一续期一次; 锁被他人持有时调用 onLost
+// 返回的函数用于停止看门狗
+func (l *taskLock) keepAlive(ctx context.Context, onLost func()) (stop func()) {
+	return keepAlive(ctx, l.ttl, l.renew, onLost)
+}
+
+// keepAlive 每隔 ttl 的三分之一调用一次 renew, renew 返回 false 时调用 onLost 并退出
+func keepAlive(ctx context.Context, ttl time.Duration, renew func(context.Context) (bool, error), onLost func()) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ok, err := renew(ctx)
+				if err == nil && !ok {
+					onLost()
+					return
+				}
+				// 网络错误时保留锁, 等待下一次续期
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
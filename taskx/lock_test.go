@@ -0,0 +1,87 @@
+package taskx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskLockKeepAlive(t *testing.T) {
+	tm := newTestManager(t, WithLockTTL(300*time.Millisecond))
+	ctx := context.Background()
+
+	held := make(chan bool, 1)
+	var job *Job
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "long"},
+		execute: func(ctx context.Context) error {
+			// 执行时间远超锁的有效期, 看门狗续期后锁仍被持有
+			time.Sleep(time.Second)
+			_, err := tm.broker.Get(ctx, tm.keyManager.TaskLockKey(job.ID))
+			held <- err == nil
+			return ctx.Err()
+		},
+	}))
+
+	var err error
+	job, err = tm.Enqueue(ctx, "long")
+	assert.NoError(t, err)
+	tm.Start()
+
+	select {
+	case ok := <-held:
+		assert.True(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not run")
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusCompleted
+	})
+
+	// 执行结束后锁被立即释放
+	_, err = tm.broker.Get(ctx, tm.keyManager.TaskLockKey(job.ID))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestTaskLockLost(t *testing.T) {
+	tm := newTestManager(t, WithLockTTL(300*time.Millisecond))
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "long", RetryCount: -1},
+		execute: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return ctx.Err()
+		},
+	}))
+
+	job, err := tm.Enqueue(ctx, "long")
+	assert.NoError(t, err)
+	tm.Start()
+	<-started
+
+	// 锁被其他节点持有后, 下一次续期失败并取消执行
+	assert.NoError(t, tm.broker.Set(ctx, tm.keyManager.TaskLockKey(job.ID), "other", time.Minute))
+	select {
+	case err := <-stopped:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("task was not cancelled after losing its lock")
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusFailed
+	})
+
+	// 释放时不会删除他人持有的锁
+	value, err := tm.broker.Get(ctx, tm.keyManager.TaskLockKey(job.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, "other", value)
+}
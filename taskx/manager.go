@@ -2,75 +2,243 @@ package taskx
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/go-redis/redis/v8"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 )
 
 type TaskManager struct {
-	redis      *redis.Client
+	broker     Broker
 	keyManager *KeyManager
 	tasks      map[string]Task
+	schedules  map[string]*scheduleEntry
 	hooks      []TaskHook
+	// workers 与 retiring 由 workersMu 保护, 自动伸缩时会在运行期间增减
 	workers    []*Worker
+	retiring   []*Worker
+	workersMu  sync.RWMutex
+	workerSeq  int
 	workerSize int
 	poolSize   int
-	mu         sync.RWMutex
+	backoff    Backoff
+	statusTTL  time.Duration
+	lockTTL    time.Duration
+	// workflowTTL 运行中工作流状态的保留时间
+	workflowTTL time.Duration
+	codec       Codec
+	queues      []Queue
+	strict      bool
+	tagLimits   map[string]int
+	elector     *LeaderElector
+	// publishProgress 是否通过 pub/sub 广播任务进度
+	publishProgress bool
+	metrics         *Metrics
+	tracer          Tracer
+	autoscale       *AutoscaleConfig
+	// handler 包含全部中间件的执行入口
+	handler Handler
+	// blockingDispatch 为 true 时使用阻塞派发, dispatchInterval 为轮询间隔或阻塞派发的兜底轮询间隔
+	blockingDispatch bool
+	dispatchInterval time.Duration
+	// freed 在 Worker 空出名额时收到通知, 用于唤醒阻塞派发
+	freed chan struct{}
+	// drained 在阻塞派发停止后遗留的阻塞命令全部返回时关闭
+	drained   chan struct{}
+	runningMu sync.Mutex
+	running   map[string]*runningJob
+	mu        sync.RWMutex
+	started   bool
+	// ctx 控制调度、派发等后台循环, execCtx 控制任务执行, 关闭时先后取消
 	ctx        context.Context
 	cancel     context.CancelFunc
+	execCtx    context.Context
+	execCancel context.CancelFunc
+	loops      sync.WaitGroup
+	stopOnce   sync.Once
 }
 
-func NewTaskManager(redisClient *redis.Client, opts ...Option) *TaskManager {
+// NewTaskManager 使用 Redis 作为 Broker 创建任务管理器
+// redisClient 可以是 *redis.Client、*redis.ClusterClient 或 Sentinel 模式的 *redis.Client
+func NewTaskManager(redisClient redis.UniversalClient, opts ...Option) *TaskManager {
+	return NewTaskManagerWithBroker(NewRedisBroker(redisClient), opts...)
+}
+
+// NewTaskManagerWithBroker 使用自定义 Broker 创建任务管理器, 测试与单进程部署可使用 MemoryBroker
+func NewTaskManagerWithBroker(broker Broker, opts ...Option) *TaskManager {
 	options := DefaultOptions()
 	for _, opt := range opts {
 		opt(&options)
 	}
+	if options.LockTTL <= 0 {
+		options.LockTTL = time.Second * defaultLockTimeout
+	}
+	if options.Codec == nil {
+		options.Codec = JSONCodec{}
+	}
+	if options.DispatchInterval <= 0 {
+		options.DispatchInterval = time.Second * defaultDispatchInterval
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	execCtx, execCancel := context.WithCancel(context.Background())
 
 	tm := &TaskManager{
-		redis:      redisClient,
-		keyManager: NewKeyManager(options.Namespace),
-		tasks:      make(map[string]Task),
-		hooks:      options.Hooks,
-		workerSize: options.WorkerSize,
-		poolSize:   options.PoolSize,
-		ctx:        ctx,
-		cancel:     cancel,
+		broker:           broker,
+		keyManager:       NewKeyManager(options.Namespace),
+		tasks:            make(map[string]Task),
+		schedules:        make(map[string]*scheduleEntry),
+		running:          make(map[string]*runningJob),
+		hooks:            options.Hooks,
+		workerSize:       options.WorkerSize,
+		poolSize:         options.PoolSize,
+		backoff:          options.RetryBackoff,
+		statusTTL:        options.StatusTTL,
+		workflowTTL:      options.WorkflowTTL,
+		lockTTL:          options.LockTTL,
+		codec:            options.Codec,
+		queues:           normalizeQueues(options.Queues),
+		strict:           options.StrictPriority,
+		tagLimits:        options.TagConcurrency,
+		publishProgress:  options.PublishProgress,
+		metrics:          newMetrics(options.DurationBuckets, options.LatencyBuckets),
+		tracer:           options.Tracer,
+		autoscale:        options.Autoscale.normalize(),
+		blockingDispatch: options.BlockingDispatch,
+		dispatchInterval: options.DispatchInterval,
+		freed:            make(chan struct{}, 1),
+		drained:          make(chan struct{}),
+		ctx:              ctx,
+		cancel:           cancel,
+		execCtx:          execCtx,
+		execCancel:       execCancel,
 	}
 
+	tm.handler = chainMiddlewares(options.Middlewares, tm.execute)
+
+	// 定时触发、延迟任务提升与回收只由 Leader 执行
+	tm.elector = tm.NewLeaderElector("scheduler", WithLeaseTTL(options.LeaderLeaseTTL))
+
 	tm.initWorkers()
 
 	return tm
 }
 
+// initWorkers 创建处理所有队列的共享 Worker, 以及各队列的专属 Worker
+// 开启自动伸缩时, 共享 Worker 的初始数量与协程池大小限制在配置的范围内
 func (tm *TaskManager) initWorkers() {
-	tm.workers = make([]*Worker, tm.workerSize)
+	if as := tm.autoscale; as != nil {
+		tm.workerSize = clampInt(tm.workerSize, as.MinWorkers, as.MaxWorkers)
+		tm.poolSize = clampInt(tm.poolSize, as.MinPoolSize, as.MaxPoolSize)
+	}
+
+	tm.workers = make([]*Worker, 0, tm.workerSize)
 	for i := 0; i < tm.workerSize; i++ {
-		tm.workers[i] = NewWorker(
-			fmt.Sprintf("worker-%d-%s", i, uuid.New().String()),
-			tm.poolSize,
-			tm,
-		)
+		tm.workers = append(tm.workers, tm.newSharedWorker())
+	}
+
+	for _, q := range tm.queues {
+		for i := 0; i < q.Workers; i++ {
+			worker := NewWorker(
+				fmt.Sprintf("worker-%s-%d-%s", q.Name, i, uuid.New().String()),
+				tm.poolSize,
+				tm,
+			)
+			worker.selector = newQueueSelector([]Queue{q}, true)
+			tm.workers = append(tm.workers, worker)
+		}
 	}
 }
 
 func (tm *TaskManager) Start() {
-	for _, worker := range tm.workers {
-		go worker.Start(tm.ctx)
+	tm.mu.Lock()
+	if tm.started || tm.ctx.Err() != nil {
+		tm.mu.Unlock()
+		return
+	}
+	tm.started = true
+	tm.mu.Unlock()
+
+	for _, worker := range tm.workerList() {
+		go worker.Start(tm.execCtx)
 	}
 
-	go tm.dispatcher()
+	if tm.autoscale != nil {
+		tm.goLoop(tm.autoscaler)
+	}
+	tm.goLoop(func() {
+		tm.elector.Run(tm.ctx)
+	})
+	tm.goLoop(tm.canceller)
+	if tm.blockingDispatch {
+		tm.goLoop(tm.blockingDispatcher)
+	} else {
+		tm.goLoop(tm.dispatcher)
+		close(tm.drained)
+	}
+	tm.goLoop(tm.scheduler)
+	tm.goLoop(tm.promoter)
+	tm.goLoop(tm.reaper)
 }
 
+// Stop 立即停止任务管理器, 正在执行的任务会被取消并放回队列
+// 需要等待任务执行完毕时使用 Shutdown
 func (tm *TaskManager) Stop() {
-	tm.cancel()
-	for _, worker := range tm.workers {
-		worker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tm.Shutdown(ctx)
+}
+
+// IsLeader 返回本实例当前是否为调度 Leader
+func (tm *TaskManager) IsLeader() bool {
+	return tm.elector.IsLeader()
+}
+
+// newSharedWorker 创建处理所有队列的 Worker
+func (tm *TaskManager) newSharedWorker() *Worker {
+	maxPoolSize := tm.poolSize
+	if tm.autoscale != nil {
+		maxPoolSize = tm.autoscale.MaxPoolSize
 	}
+	worker := newWorker(
+		fmt.Sprintf("worker-%d-%s", tm.workerSeq, uuid.New().String()),
+		tm.poolSize,
+		maxPoolSize,
+		tm,
+	)
+	tm.workerSeq++
+	worker.selector = newQueueSelector(tm.queues, tm.strict)
+	worker.shared = true
+	return worker
+}
+
+// workerList 返回当前参与派发的 Worker
+func (tm *TaskManager) workerList() []*Worker {
+	tm.workersMu.RLock()
+	defer tm.workersMu.RUnlock()
+	return append([]*Worker(nil), tm.workers...)
+}
+
+// allWorkers 返回参与派发以及缩容后仍在收尾的 Worker
+func (tm *TaskManager) allWorkers() []*Worker {
+	tm.workersMu.RLock()
+	defer tm.workersMu.RUnlock()
+
+	workers := make([]*Worker, 0, len(tm.workers)+len(tm.retiring))
+	workers = append(workers, tm.workers...)
+	return append(workers, tm.retiring...)
+}
+
+func (tm *TaskManager) goLoop(fn func()) {
+	tm.loops.Add(1)
+	go func() {
+		defer tm.loops.Done()
+		fn()
+	}()
 }
 
 func (tm *TaskManager) RegisterTask(task Task) error {
@@ -78,11 +246,34 @@ func (tm *TaskManager) RegisterTask(task Task) error {
 		return err
 	}
 
-	tm.mu.Lock()
-	defer tm.mu.Unlock()
+	if !tm.hasQueue(tm.taskQueue(task)) {
+		return ErrQueueNotFound
+	}
+
+	var entry *scheduleEntry
+	if cfg, ok := task.GetConfig().(*ScheduleTaskConfig); ok {
+		schedule, err := ParseCron(cfg.Cron)
+		if err != nil {
+			return err
+		}
+		entry = &scheduleEntry{
+			taskID:   task.GetID(),
+			queue:    tm.taskQueue(task),
+			schedule: schedule,
+			next:     schedule.Next(time.Now()),
+		}
+	}
 
+	tm.mu.Lock()
 	tm.tasks[task.GetID()] = task
-	return nil
+	if entry != nil {
+		tm.schedules[task.GetID()] = entry
+	} else {
+		delete(tm.schedules, task.GetID())
+	}
+	tm.mu.Unlock()
+
+	return tm.armTask(tm.ctx, task)
 }
 
 func (tm *TaskManager) triggerHooks(fn func(TaskHook) error) {
@@ -94,13 +285,9 @@ func (tm *TaskManager) triggerHooks(fn func(TaskHook) error) {
 	}
 }
 
-func (tm *TaskManager) acquireLock(ctx context.Context, key string) (bool, error) {
-	return tm.redis.SetNX(ctx, key, "1",
-		time.Second*defaultLockTimeout).Result()
-}
-
+// dispatcher 每隔 dispatchInterval 轮询一次队列, 开启阻塞派发时由 blockingDispatcher 代替
 func (tm *TaskManager) dispatcher() {
-	ticker := time.NewTicker(time.Second)
+	ticker := time.NewTicker(tm.dispatchInterval)
 	defer ticker.Stop()
 
 	for {
@@ -113,7 +300,7 @@ func (tm *TaskManager) dispatcher() {
 				continue
 			}
 
-			tm.dispatchTasks(activeWorkers)
+			tm.dispatchTasks(activeWorkers, tm.loadPauseState())
 		}
 	}
 }
@@ -121,9 +308,13 @@ func (tm *TaskManager) dispatcher() {
 func (tm *TaskManager) getActiveWorkers() []*Worker {
 	var activeWorkers []*Worker
 
-	for _, worker := range tm.workers {
+	for _, worker := range tm.workerList() {
 		heartbeatKey := tm.keyManager.WorkerHeartbeatKey(worker.id)
-		ts, err := tm.redis.Get(tm.ctx, heartbeatKey).Int64()
+		value, err := tm.broker.Get(tm.ctx, heartbeatKey)
+		if err != nil {
+			continue
+		}
+		ts, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			continue
 		}
@@ -136,29 +327,114 @@ func (tm *TaskManager) getActiveWorkers() []*Worker {
 	return activeWorkers
 }
 
-func (tm *TaskManager) dispatchTasks(workers []*Worker) {
-	queueKey := tm.keyManager.TaskQueueKey()
-	taskIDs, err := tm.redis.LRange(tm.ctx, queueKey, 0,
-		int64(len(workers))-1).Result()
-	if err != nil || len(taskIDs) == 0 {
-		return
-	}
+// dispatchTasks 轮流为有空闲容量的 Worker 领取任务, 每个 Worker 按自身的队列顺序依次尝试
+// 任务被原子地从队列移入 Worker 的处理列表, 执行结束后确认移除, 进程崩溃时由 reaper 放回队列
+func (tm *TaskManager) dispatchTasks(workers []*Worker, paused *pauseState) {
+	for progress := true; progress; {
+		progress = false
+		for _, worker := range workers {
+			if worker.freeSlots() <= 0 {
+				continue
+			}
 
-	for i, taskID := range taskIDs {
-		tm.mu.RLock()
-		task, exists := tm.tasks[taskID]
-		tm.mu.RUnlock()
+			for _, queue := range worker.selector.order() {
+				// 暂停的队列不领取, 任务留在队列中
+				if paused.queuePaused(queue) {
+					continue
+				}
+				queueKey := tm.keyManager.QueueKey(queue)
+				processingKey := tm.keyManager.QueueProcessingKey(worker.id, queue)
+				entry, err := tm.broker.Move(tm.ctx, queueKey, processingKey)
+				if err != nil {
+					// 队列为空或 Broker 异常, 尝试下一个队列
+					continue
+				}
 
-		if !exists {
-			continue
+				if !tm.deliver(worker, queue, entry, paused) {
+					// 本轮不再继续以免空转
+					return
+				}
+				progress = true
+				break
+			}
 		}
+	}
+}
 
-		workerIdx := i % len(workers)
-		select {
-		case workers[workerIdx].tasks <- task:
-			tm.redis.LRem(tm.ctx, queueKey, 1, taskID)
-		default:
-			// Worker队列已满，等待下次调度
+// deliver 处理已移入 worker 处理列表的队列条目, 返回 false 表示本实例无法处理该条目
+func (tm *TaskManager) deliver(worker *Worker, queue, entry string, paused *pauseState) bool {
+	processing := tm.keyManager.QueueProcessingKey(worker.id, queue)
+	d, err := tm.resolve(entry, queue)
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		// 任务已结束或已被取消, 进程在确认前崩溃或与取消竞争时条目会被再次领取, 直接丢弃
+		tm.broker.Ack(tm.ctx, processing, entry)
+		return true
+	case errors.Is(err, ErrInvalidJob):
+		// 记录无法解析, 移入死信队列以免反复派发
+		tm.discardInvalid(processing, queue, entry, err)
+		return true
+	case err != nil:
+		// 本实例无法处理或 Broker 异常, 放回队列留给其他实例
+		handOff(tm.ctx, tm.broker, processing, entry, func() error {
+			return tm.broker.Push(tm.ctx, tm.keyManager.QueueKey(queue), entry)
+		})
+		return false
+	}
+
+	// 暂停的任务移入暂停列表, 恢复时放回队列
+	if paused.taskPaused(d.job.TaskID) {
+		tm.parkJob(tm.ctx, worker.id, queue, entry, d.task)
+		return true
+	}
+
+	// 超出限流的任务延后派发, 由 promoter 到期后放回队列
+	if delay := tm.throttle(tm.ctx, d); delay > 0 {
+		tm.deferJob(tm.ctx, worker.id, entry, d.job, delay)
+		return true
+	}
+
+	// 调度协程是唯一的生产者, 容量检查后发送不会阻塞; Worker 已被缩容时放回队列
+	if !worker.offer(d) {
+		tm.requeue(context.Background(), worker.id, d.job)
+	}
+	return true
+}
+
+// resolve 把从 queue 领取的队列条目解析为可执行的任务
+// 没有记录的 JobID 返回 ErrJobNotFound, 任务未在本实例注册时返回 ErrTaskNotFound
+func (tm *TaskManager) resolve(entry, queue string) (delivery, error) {
+	job, err := tm.loadJob(tm.ctx, entry)
+	if err != nil {
+		return delivery{}, err
+	}
+	job.Queue = queue
+
+	tm.mu.RLock()
+	task, exists := tm.tasks[job.TaskID]
+	tm.mu.RUnlock()
+
+	if !exists {
+		if job.ID == job.TaskID && isJobID(entry) {
+			return delivery{}, ErrJobNotFound
 		}
+		return delivery{}, ErrTaskNotFound
+	}
+	if job.ExecuteAt.IsZero() {
+		job.ExecuteAt = tm.dueAt(tm.ctx, job.TaskID)
+	}
+	return delivery{task: task, job: job}, nil
+}
+
+// discardInvalid 把记录无法解析的条目移入死信队列并删除记录
+func (tm *TaskManager) discardInvalid(processing, queue, entry string, cause error) {
+	job := &Job{ID: entry, Queue: queue}
+	now := time.Now()
+	result := &TaskResult{JobID: entry, Attempt: 1, Status: TaskStatusFailed, StartTime: now, EndTime: now, Error: cause}
+	err := handOff(tm.ctx, tm.broker, processing, entry, func() error {
+		return tm.deadLetter(tm.ctx, job, result)
+	})
+	if err == nil {
+		tm.broker.Del(tm.ctx, tm.keyManager.TaskJobKey(entry))
 	}
 }
@@ -0,0 +1,440 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTask struct {
+	BaseTaskConfig
+	execute func(ctx context.Context) error
+}
+
+func (t *testTask) Execute(ctx context.Context) error { return t.execute(ctx) }
+func (t *testTask) GetID() string                     { return t.ID }
+func (t *testTask) GetType() TaskType                 { return TaskTypeOnce }
+func (t *testTask) GetConfig() TaskConfig             { return &t.BaseTaskConfig }
+
+func newTestManager(t *testing.T, opts ...Option) *TaskManager {
+	opts = append([]Option{WithWorkerSize(1), WithPoolSize(2)}, opts...)
+	tm := NewTaskManagerWithBroker(NewMemoryBroker(), opts...)
+	t.Cleanup(tm.Stop)
+	return tm
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within %v", timeout)
+}
+
+func TestTaskManagerEnqueue(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "echo", Timeout: time.Second},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}))
+
+	_, err := tm.Enqueue(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+
+	job, err := tm.Enqueue(ctx, "echo", WithDedupKey("once"))
+	assert.NoError(t, err)
+	assert.Equal(t, "echo", job.TaskID)
+
+	_, err = tm.Enqueue(ctx, "echo", WithDedupKey("once"))
+	assert.ErrorIs(t, err, ErrDuplicateJob)
+
+	status, err := tm.GetStatus(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusPending, status.Status)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusCompleted
+	})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	// 任务结束后去重键被释放
+	_, err = tm.Enqueue(ctx, "echo", WithDedupKey("once"))
+	assert.NoError(t, err)
+}
+
+func TestTaskManagerRetryAndDeadLetter(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{
+			ID:         "flaky",
+			RetryCount: 1,
+			Backoff:    &FixedBackoff{Interval: time.Millisecond},
+		},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return errors.New("boom")
+		},
+	}))
+
+	tm.Start()
+	job, err := tm.Enqueue(ctx, "flaky")
+	assert.NoError(t, err)
+
+	waitFor(t, 10*time.Second, func() bool {
+		n, _ := tm.CountDeadLetters(ctx)
+		return n == 1
+	})
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+
+	letters, err := tm.ListDeadLetters(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, letters, 1)
+	assert.Equal(t, job.ID, letters[0].Job.ID)
+	assert.Equal(t, "boom", letters[0].LastResult.Error)
+	assert.Equal(t, 2, letters[0].LastResult.Attempt)
+	assert.Len(t, letters[0].Job.Failures, 2)
+
+	status, err := tm.GetStatus(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusFailed, status.Status)
+
+	requeued, err := tm.RequeueDeadLetter(ctx, letters[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, requeued.Retried)
+
+	_, err = tm.RequeueDeadLetter(ctx, letters[0].ID)
+	assert.ErrorIs(t, err, ErrDeadLetterNotFound)
+}
+
+var errInjected = errors.New("injected broker error")
+
+// faultyBroker 按操作与键注入 Broker 错误
+type faultyBroker struct {
+	Broker
+	mu    sync.Mutex
+	fails map[string]int
+}
+
+func newFaultyBroker() *faultyBroker {
+	return &faultyBroker{Broker: NewMemoryBroker(), fails: make(map[string]int)}
+}
+
+// failNext 让接下来 n 次对 key 的 op 操作返回 errInjected
+func (b *faultyBroker) failNext(op, key string, n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fails[op+" "+key] += n
+}
+
+func (b *faultyBroker) fault(op, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fails[op+" "+key] > 0 {
+		b.fails[op+" "+key]--
+		return errInjected
+	}
+	return nil
+}
+
+func (b *faultyBroker) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if err := b.fault("SetNX", key); err != nil {
+		return false, err
+	}
+	return b.Broker.SetNX(ctx, key, value, ttl)
+}
+
+func (b *faultyBroker) Schedule(ctx context.Context, set, id string, at time.Time) error {
+	if err := b.fault("Schedule", set); err != nil {
+		return err
+	}
+	return b.Broker.Schedule(ctx, set, id, at)
+}
+
+func (b *faultyBroker) EnqueueJob(ctx context.Context, args *EnqueueArgs) (bool, error) {
+	if err := b.fault("EnqueueJob", args.Queue); err != nil {
+		return false, err
+	}
+	return b.Broker.EnqueueJob(ctx, args)
+}
+
+func TestTaskManagerLockBrokerError(t *testing.T) {
+	broker := newFaultyBroker()
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(tm.Stop)
+	ctx := context.Background()
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "echo"},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}))
+
+	job, err := tm.Enqueue(ctx, "echo", WithDedupKey("once"))
+	assert.NoError(t, err)
+	// 获取任务锁时 Broker 异常, 任务延后重新派发而不是被确认丢弃
+	broker.failNext("SetNX", tm.keyManager.TaskLockKey(job.ID), 1)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusCompleted
+	})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	_, err = tm.Enqueue(ctx, "echo", WithDedupKey("once"))
+	assert.NoError(t, err)
+}
+
+func TestRequeueDeadLetterEnqueueError(t *testing.T) {
+	broker := newFaultyBroker()
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(tm.Stop)
+	ctx := context.Background()
+
+	job := &Job{ID: "job-1", TaskID: "flaky"}
+	assert.NoError(t, tm.deadLetter(ctx, job, &TaskResult{Attempt: 1, Status: TaskStatusFailed, Error: errors.New("boom")}))
+	letters, err := tm.ListDeadLetters(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, letters, 1)
+
+	// 投递失败时死信被放回, 之后仍可重新投递
+	broker.failNext("EnqueueJob", tm.keyManager.QueueKey(DefaultQueue), 1)
+	_, err = tm.RequeueDeadLetter(ctx, letters[0].ID)
+	assert.ErrorIs(t, err, errInjected)
+	restored, err := tm.GetDeadLetter(ctx, letters[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, letters[0], restored)
+
+	requeued, err := tm.RequeueDeadLetter(ctx, letters[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, requeued.ID)
+	n, err := tm.CountDeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestReapExpiredWorkers(t *testing.T) {
+	broker := NewMemoryBroker()
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	defer tm.Stop()
+	ctx := context.Background()
+	km := tm.keyManager
+
+	now := time.Now()
+	assert.NoError(t, broker.Schedule(ctx, km.WorkerRegistryKey(), "dead", now.Add(-time.Hour)))
+	assert.NoError(t, broker.Schedule(ctx, km.WorkerRegistryKey(), "alive", now))
+	assert.NoError(t, broker.Push(ctx, km.TaskProcessingKey("dead"), "job-1"))
+	assert.NoError(t, broker.Push(ctx, km.TaskProcessingKey("alive"), "job-2"))
+
+	tm.reapExpiredWorkers(now)
+
+	queued, _ := broker.Peek(ctx, km.TaskQueueKey(), 10)
+	assert.Equal(t, []string{"job-1"}, queued)
+
+	processing, _ := broker.Peek(ctx, km.TaskProcessingKey("alive"), 10)
+	assert.Equal(t, []string{"job-2"}, processing)
+
+	workers, _ := broker.DueMembers(ctx, km.WorkerRegistryKey(), now, 10)
+	assert.Equal(t, []string{"alive"}, workers)
+}
+
+func TestTaskManagerStaleEntries(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+	km := tm.keyManager
+	queue := tm.queues[0].Name
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "echo"},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}))
+	tm.Start()
+
+	job, err := tm.Enqueue(ctx, "echo")
+	assert.NoError(t, err)
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusCompleted
+	})
+
+	// 已结束任务的条目被再次放回(如确认前崩溃后被 reaper 回收), 以及记录损坏的条目
+	assert.NoError(t, tm.broker.Push(ctx, km.QueueKey(queue), job.ID))
+	assert.NoError(t, tm.broker.Set(ctx, km.TaskJobKey("corrupt"), "{", 0))
+	assert.NoError(t, tm.broker.Push(ctx, km.QueueKey(queue), "corrupt"))
+
+	waitFor(t, 5*time.Second, func() bool {
+		n, _ := tm.broker.Len(ctx, km.QueueKey(queue))
+		dead, _ := tm.CountDeadLetters(ctx)
+		return n == 0 && dead == 1
+	})
+	for _, w := range tm.allWorkers() {
+		n, _ := tm.broker.Len(ctx, km.QueueProcessingKey(w.id, queue))
+		assert.Zero(t, n)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	dead, err := tm.ListDeadLetters(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "corrupt", dead[0].Job.ID)
+	_, err = tm.broker.Get(ctx, km.TaskJobKey("corrupt"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+type emailTask struct {
+	BaseTaskConfig
+	sent chan string
+}
+
+func (t *emailTask) Execute(ctx context.Context) error { return errors.New("payload expected") }
+func (t *emailTask) GetID() string                     { return t.ID }
+func (t *emailTask) GetType() TaskType                 { return TaskTypeOnce }
+func (t *emailTask) GetConfig() TaskConfig             { return &t.BaseTaskConfig }
+
+func (t *emailTask) ExecuteWithPayload(ctx context.Context, payload Payload) error {
+	var args struct {
+		To string `json:"to"`
+	}
+	if err := payload.Decode(&args); err != nil {
+		return err
+	}
+
+	fromCtx, ok := PayloadFromContext(ctx)
+	if !ok || string(fromCtx.Bytes()) != string(payload.Bytes()) {
+		return errors.New("payload missing from context")
+	}
+
+	t.sent <- args.To
+	return nil
+}
+
+func TestTaskManagerPayload(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	task := &emailTask{BaseTaskConfig: BaseTaskConfig{ID: "send-email"}, sent: make(chan string, 3)}
+	assert.NoError(t, tm.RegisterTask(task))
+	tm.Start()
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		_, err := tm.Enqueue(ctx, "send-email", WithPayload(map[string]string{"to": to}))
+		assert.NoError(t, err)
+	}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		select {
+		case to := <-task.sent:
+			got = append(got, to)
+		case <-time.After(5 * time.Second):
+			t.Fatal("payload task not executed")
+		}
+	}
+	assert.ElementsMatch(t, []string{"a@example.com", "b@example.com", "c@example.com"}, got)
+}
+
+func TestTaskManagerRateLimit(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{
+			ID:        "quota",
+			RateLimit: &RateLimit{Limit: 1, Window: time.Hour, Algorithm: SlidingWindow},
+		},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}))
+	assert.ErrorIs(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "bad", RateLimit: &RateLimit{Limit: 0, Window: time.Second}},
+	}), ErrInvalidConfig)
+
+	first, err := tm.Enqueue(ctx, "quota")
+	assert.NoError(t, err)
+	second, err := tm.Enqueue(ctx, "quota")
+	assert.NoError(t, err)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		delayed, _ := tm.broker.DueMembers(ctx, tm.keyManager.TaskDelayedKey(), time.Now().Add(2*time.Hour), 10)
+		return atomic.LoadInt32(&runs) == 1 && len(delayed) == 1
+	})
+
+	// 超出限流的任务被延后, 而不是失败
+	delayed, _ := tm.broker.DueMembers(ctx, tm.keyManager.TaskDelayedKey(), time.Now().Add(2*time.Hour), 10)
+	assert.Equal(t, []string{second.ID}, delayed)
+	status, err := tm.GetStatus(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusPending, status.Status)
+
+	status, err = tm.GetStatus(ctx, first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, status.Status)
+}
+
+func TestTaskManagerTagConcurrency(t *testing.T) {
+	tm := newTestManager(t, WithPoolSize(3), WithTagConcurrency("report", 1))
+	ctx := context.Background()
+
+	var running, peak, done int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "daily-report", Tags: []string{"report"}},
+		execute: func(ctx context.Context) error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(200 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+			return nil
+		},
+	}))
+
+	for i := 0; i < 3; i++ {
+		_, err := tm.Enqueue(ctx, "daily-report")
+		assert.NoError(t, err)
+	}
+
+	tm.Start()
+	waitFor(t, 10*time.Second, func() bool {
+		return atomic.LoadInt32(&done) == 3
+	})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+
+	count, err := tm.CountDeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+}
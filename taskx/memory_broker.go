@@ -0,0 +1,697 @@
+package taskx
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryBroker 基于进程内存的 Broker 实现, 适用于单元测试与单进程部署
+// 数据不会持久化, 也无法在多个进程之间共享
+type MemoryBroker struct {
+	mu       sync.Mutex
+	values   map[string]memoryValue
+	lists    map[string][]string
+	sets     map[string]map[string]int64
+	fields   map[string]map[string]string
+	expiries map[string]time.Time
+	archives map[string]*memoryArchive
+	limiters map[string]*memoryLimiter
+	channels map[string]map[chan string]struct{}
+	// pushed 在任意列表写入新元素时关闭并替换, 用于唤醒 BlockingMove
+	pushed chan struct{}
+}
+
+type memoryValue struct {
+	value    string
+	expireAt time.Time
+}
+
+type memoryArchive struct {
+	scores map[string]int64
+	data   map[string]string
+}
+
+// memoryLimiter 保存令牌桶的令牌数与滑动窗口的放行记录
+type memoryLimiter struct {
+	tokens float64
+	last   time.Time
+	hits   []time.Time
+}
+
+var _ Broker = (*MemoryBroker)(nil)
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		values:   make(map[string]memoryValue),
+		lists:    make(map[string][]string),
+		sets:     make(map[string]map[string]int64),
+		fields:   make(map[string]map[string]string),
+		expiries: make(map[string]time.Time),
+		archives: make(map[string]*memoryArchive),
+		limiters: make(map[string]*memoryLimiter),
+		channels: make(map[string]map[chan string]struct{}),
+		pushed:   make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) Push(ctx context.Context, queue, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pushLocked(queue, id)
+	return nil
+}
+
+func (b *MemoryBroker) pushLocked(queue, id string) {
+	b.lists[queue] = append([]string{id}, b.lists[queue]...)
+	close(b.pushed)
+	b.pushed = make(chan struct{})
+}
+
+func (b *MemoryBroker) Peek(ctx context.Context, queue string, n int) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := b.lists[queue]
+	if n > len(list) {
+		n = len(list)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	return append([]string(nil), list[:n]...), nil
+}
+
+func (b *MemoryBroker) Len(ctx context.Context, queue string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return int64(len(b.lists[queue])), nil
+}
+
+func (b *MemoryBroker) Remove(ctx context.Context, queue, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(queue, id)
+	return nil
+}
+
+func (b *MemoryBroker) removeLocked(queue, id string) bool {
+	list := b.lists[queue]
+	for i, item := range list {
+		if item == id {
+			b.lists[queue] = append(list[:i:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (b *MemoryBroker) Move(ctx context.Context, queue, processing string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, ok := b.popLocked(queue)
+	if !ok {
+		return "", ErrQueueEmpty
+	}
+	b.pushLocked(processing, id)
+	return id, nil
+}
+
+// BlockingMove 队列为空时等待任意列表写入后重试, 直到超时或 ctx 结束
+func (b *MemoryBroker) BlockingMove(ctx context.Context, queue, processing string, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		b.mu.Lock()
+		id, ok := b.popLocked(queue)
+		if ok {
+			b.pushLocked(processing, id)
+			b.mu.Unlock()
+			return id, nil
+		}
+		pushed := b.pushed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timer.C:
+			return "", ErrQueueEmpty
+		case <-pushed:
+		}
+	}
+}
+
+// popLocked 弹出队列尾部的元素
+func (b *MemoryBroker) popLocked(queue string) (string, bool) {
+	list := b.lists[queue]
+	if len(list) == 0 {
+		return "", false
+	}
+	id := list[len(list)-1]
+	b.lists[queue] = list[:len(list)-1]
+	return id, true
+}
+
+func (b *MemoryBroker) Ack(ctx context.Context, processing, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeLocked(processing, id)
+	return nil
+}
+
+func (b *MemoryBroker) RequeueAll(ctx context.Context, processing, queue string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := 0
+	for {
+		id, ok := b.popLocked(processing)
+		if !ok {
+			return n, nil
+		}
+		b.pushLocked(queue, id)
+		n++
+	}
+}
+
+func (b *MemoryBroker) Schedule(ctx context.Context, set, id string, at time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.scheduleLocked(set, id, at, true)
+	return nil
+}
+
+func (b *MemoryBroker) ScheduleIfAbsent(ctx context.Context, set, id string, at time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.scheduleLocked(set, id, at, false)
+	return nil
+}
+
+func (b *MemoryBroker) scheduleLocked(set, id string, at time.Time, replace bool) {
+	members, ok := b.sets[set]
+	if !ok {
+		members = make(map[string]int64)
+		b.sets[set] = members
+	}
+	if _, exists := members[id]; exists && !replace {
+		return
+	}
+	members[id] = at.UnixMilli()
+}
+
+func (b *MemoryBroker) Unschedule(ctx context.Context, set, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.sets[set], id)
+	return nil
+}
+
+func (b *MemoryBroker) ScheduledLen(ctx context.Context, set string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return int64(len(b.sets[set])), nil
+}
+
+func (b *MemoryBroker) DueMembers(ctx context.Context, set string, before time.Time, limit int) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	due := sortedMembers(b.sets[set], func(score int64) bool {
+		return score <= before.UnixMilli()
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (b *MemoryBroker) PromoteDue(ctx context.Context, set, queue string, now time.Time, limit int) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	due := sortedMembers(b.sets[set], func(score int64) bool {
+		return score <= now.UnixMilli()
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for _, id := range due {
+		delete(b.sets[set], id)
+		b.pushLocked(queue, id)
+	}
+	return len(due), nil
+}
+
+// sortedMembers 按分数升序返回满足条件的成员, 分数相同时按成员排序
+func sortedMembers(members map[string]int64, match func(score int64) bool) []string {
+	var ids []string
+	for id, score := range members {
+		if match(score) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if members[ids[i]] != members[ids[j]] {
+			return members[ids[i]] < members[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func (b *MemoryBroker) EnqueueJob(ctx context.Context, args *EnqueueArgs) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range []string{args.DedupKey, args.UniqueKey} {
+		if _, exists := b.getLocked(key); key != "" && exists {
+			return false, nil
+		}
+	}
+	if args.DedupKey != "" {
+		b.setNXLocked(args.DedupKey, args.JobID, args.DedupTTL)
+	}
+	if args.UniqueKey != "" {
+		b.setNXLocked(args.UniqueKey, args.JobID, args.UniqueTTL)
+	}
+
+	b.values[args.JobKey] = memoryValue{value: args.JobData}
+	if args.At.IsZero() {
+		b.pushLocked(args.Queue, args.JobID)
+	} else {
+		b.scheduleLocked(args.Queue, args.JobID, args.At, true)
+	}
+	return true, nil
+}
+
+// getLocked 读取未过期的键值, 已过期的键会被清理
+func (b *MemoryBroker) getLocked(key string) (memoryValue, bool) {
+	v, ok := b.values[key]
+	if !ok {
+		return memoryValue{}, false
+	}
+	if !v.expireAt.IsZero() && !time.Now().Before(v.expireAt) {
+		delete(b.values, key)
+		return memoryValue{}, false
+	}
+	return v, true
+}
+
+func (b *MemoryBroker) setNXLocked(key, value string, ttl time.Duration) bool {
+	if _, ok := b.getLocked(key); ok {
+		return false
+	}
+	b.values[key] = memoryValue{value: value, expireAt: expireAt(ttl)}
+	return true
+}
+
+func expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (b *MemoryBroker) Get(ctx context.Context, key string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	v, ok := b.getLocked(key)
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return v.value, nil
+}
+
+func (b *MemoryBroker) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.values[key] = memoryValue{value: value, expireAt: expireAt(ttl)}
+	return nil
+}
+
+func (b *MemoryBroker) Del(ctx context.Context, keys ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		delete(b.values, key)
+		delete(b.lists, key)
+		delete(b.sets, key)
+		delete(b.fields, key)
+		delete(b.expiries, key)
+		delete(b.archives, key)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.setNXLocked(key, value, ttl), nil
+}
+
+func (b *MemoryBroker) CompareAndDelete(ctx context.Context, key, value string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	v, ok := b.getLocked(key)
+	if !ok || v.value != value {
+		return false, nil
+	}
+	delete(b.values, key)
+	return true, nil
+}
+
+func (b *MemoryBroker) CompareAndExpire(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	v, ok := b.getLocked(key)
+	if !ok || v.value != value {
+		return false, nil
+	}
+	v.expireAt = expireAt(ttl)
+	b.values[key] = v
+	return true, nil
+}
+
+func (b *MemoryBroker) SetFields(ctx context.Context, key string, fields map[string]string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, ok := b.getFieldsLocked(key)
+	if !ok {
+		current = make(map[string]string, len(fields))
+		b.fields[key] = current
+	}
+	for k, v := range fields {
+		current[k] = v
+	}
+	if ttl > 0 {
+		b.expiries[key] = expireAt(ttl)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) GetFields(ctx context.Context, key string) (map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, _ := b.getFieldsLocked(key)
+	fields := make(map[string]string, len(current))
+	for k, v := range current {
+		fields[k] = v
+	}
+	return fields, nil
+}
+
+func (b *MemoryBroker) getFieldsLocked(key string) (map[string]string, bool) {
+	if at, ok := b.expiries[key]; ok && !time.Now().Before(at) {
+		delete(b.fields, key)
+		delete(b.expiries, key)
+	}
+	current, ok := b.fields[key]
+	return current, ok
+}
+
+func (b *MemoryBroker) ArchiveAdd(ctx context.Context, key, id, data string, at time.Time, max int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	archive, ok := b.archives[key]
+	if !ok {
+		archive = &memoryArchive{scores: make(map[string]int64), data: make(map[string]string)}
+		b.archives[key] = archive
+	}
+	archive.scores[id] = at.UnixMilli()
+	archive.data[id] = data
+
+	if overflow := len(archive.scores) - max; overflow > 0 {
+		oldest := sortedMembers(archive.scores, func(int64) bool { return true })
+		for _, expired := range oldest[:overflow] {
+			delete(archive.scores, expired)
+			delete(archive.data, expired)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) ArchiveList(ctx context.Context, key string, offset, limit int) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	archive, ok := b.archives[key]
+	if !ok || limit <= 0 {
+		return nil, nil
+	}
+
+	ids := sortedMembers(archive.scores, func(int64) bool { return true })
+	for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+	if offset >= len(ids) {
+		return nil, nil
+	}
+	ids = ids[offset:]
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	records := make([]string, len(ids))
+	for i, id := range ids {
+		records[i] = archive.data[id]
+	}
+	return records, nil
+}
+
+func (b *MemoryBroker) ArchiveGet(ctx context.Context, key, id string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if archive, ok := b.archives[key]; ok {
+		if data, ok := archive.data[id]; ok {
+			return data, nil
+		}
+	}
+	return "", ErrKeyNotFound
+}
+
+func (b *MemoryBroker) ArchiveRemove(ctx context.Context, key string, ids ...string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	archive, ok := b.archives[key]
+	if !ok {
+		return 0, nil
+	}
+
+	if len(ids) == 0 {
+		delete(b.archives, key)
+		return int64(len(archive.scores)), nil
+	}
+
+	var removed int64
+	for _, id := range ids {
+		if _, ok := archive.scores[id]; ok {
+			delete(archive.scores, id)
+			delete(archive.data, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (b *MemoryBroker) ArchiveLen(ctx context.Context, key string) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if archive, ok := b.archives[key]; ok {
+		return int64(len(archive.scores)), nil
+	}
+	return 0, nil
+}
+
+func (b *MemoryBroker) Allow(ctx context.Context, key string, limit *RateLimit, now time.Time) (time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	limiter, ok := b.limiters[key]
+	if !ok {
+		limiter = &memoryLimiter{tokens: float64(limit.Limit), last: now}
+		b.limiters[key] = limiter
+	}
+
+	if limit.Algorithm == SlidingWindow {
+		start := now.Add(-limit.Window)
+		hits := limiter.hits[:0]
+		for _, hit := range limiter.hits {
+			if hit.After(start) {
+				hits = append(hits, hit)
+			}
+		}
+		limiter.hits = hits
+
+		if len(hits) < limit.Limit {
+			limiter.hits = append(limiter.hits, now)
+			return 0, nil
+		}
+		wait := hits[0].Add(limit.Window).Sub(now)
+		if wait < time.Millisecond {
+			wait = time.Millisecond
+		}
+		return wait, nil
+	}
+
+	rate := float64(limit.Limit) / float64(limit.Window)
+	if now.After(limiter.last) {
+		limiter.tokens += float64(now.Sub(limiter.last)) * rate
+		if limiter.tokens > float64(limit.Limit) {
+			limiter.tokens = float64(limit.Limit)
+		}
+		limiter.last = now
+	}
+	if limiter.tokens >= 1 {
+		limiter.tokens--
+		return 0, nil
+	}
+	// 与 Redis 实现一致, 按毫秒向上取整
+	return time.Duration(math.Ceil((1-limiter.tokens)/rate/float64(time.Millisecond))) * time.Millisecond, nil
+}
+
+func (b *MemoryBroker) AcquireSemaphores(ctx context.Context, keys []string, limits []int, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for i, key := range keys {
+		holders := b.sets[key]
+		for member, expireAt := range holders {
+			if expireAt <= now {
+				delete(holders, member)
+			}
+		}
+		if _, held := holders[token]; !held && len(holders) >= limits[i] {
+			return false, nil
+		}
+	}
+
+	for _, key := range keys {
+		b.scheduleLocked(key, token, time.UnixMilli(now).Add(ttl), true)
+	}
+	return true, nil
+}
+
+func (b *MemoryBroker) RenewSemaphores(ctx context.Context, keys []string, token string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	held := true
+	for _, key := range keys {
+		expireAt, ok := b.sets[key][token]
+		if !ok || expireAt <= now.UnixMilli() {
+			held = false
+			continue
+		}
+		b.scheduleLocked(key, token, now.Add(ttl), true)
+	}
+	return held, nil
+}
+
+func (b *MemoryBroker) ReleaseSemaphores(ctx context.Context, keys []string, token string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, key := range keys {
+		delete(b.sets[key], token)
+	}
+	return nil
+}
+
+func (b *MemoryBroker) AcquireLease(ctx context.Context, key, holder string, ttl time.Duration) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lease, ok := b.getFieldsLocked(key)
+	if ok && lease["holder"] == holder {
+		b.expiries[key] = time.Now().Add(ttl)
+		return strconv.ParseInt(lease["token"], 10, 64)
+	}
+	if ok {
+		return 0, nil
+	}
+
+	var token int64
+	if current, ok := b.getLocked(fencingKey(key)); ok {
+		token, _ = strconv.ParseInt(current.value, 10, 64)
+	}
+	token++
+	b.values[fencingKey(key)] = memoryValue{value: strconv.FormatInt(token, 10)}
+	b.fields[key] = map[string]string{"holder": holder, "token": strconv.FormatInt(token, 10)}
+	b.expiries[key] = time.Now().Add(ttl)
+	return token, nil
+}
+
+func (b *MemoryBroker) ReleaseLease(ctx context.Context, key, holder string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lease, ok := b.getFieldsLocked(key); ok && lease["holder"] == holder {
+		delete(b.fields, key)
+		delete(b.expiries, key)
+	}
+	return nil
+}
+
+// Publish 向当前的订阅者广播消息, 订阅者处理不及时时丢弃消息, 与 Redis pub/sub 一样不保证送达
+func (b *MemoryBroker) Publish(ctx context.Context, channel, message string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.channels[channel] {
+		select {
+		case ch <- message:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan string, 64)
+	if b.channels[channel] == nil {
+		b.channels[channel] = make(map[chan string]struct{})
+	}
+	b.channels[channel][ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.channels[channel], ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+	return ch, nil
+}
@@ -0,0 +1,12 @@
+package taskx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	testBrokerContract(t, func(t *testing.T) *brokerHarness {
+		return &brokerHarness{broker: NewMemoryBroker(), elapse: time.Sleep}
+	})
+}
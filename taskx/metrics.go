@@ -0,0 +1,347 @@
+package taskx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets 执行耗时直方图的默认分桶, 单位秒
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// DefaultLatencyBuckets 派发延迟直方图的默认分桶, 单位秒
+var DefaultLatencyBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Metrics 进程内的任务指标, 不依赖外部采集端
+// 计数与直方图只统计本实例执行的任务, 队列深度在采集时从 Broker 读取, 反映整个集群
+type Metrics struct {
+	durationBuckets []float64
+	latencyBuckets  []float64
+
+	mu        sync.Mutex
+	tasks     map[string]*taskMetrics
+	latencies map[string]*histogram
+
+	active int64
+}
+
+type taskMetrics struct {
+	// results 按结果(completed/failed/timeout/panic/cancelled)计数
+	results  map[string]uint64
+	retries  uint64
+	duration *histogram
+}
+
+// histogram 累积分桶直方图
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// HistogramSnapshot 直方图快照, Counts[i] 为不大于 Buckets[i] 的观测次数
+type HistogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Sum     float64
+	Count   uint64
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	return HistogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  append([]uint64(nil), h.counts...),
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+}
+
+// TaskMetricsSnapshot 单个任务的指标快照
+type TaskMetricsSnapshot struct {
+	Results  map[string]uint64
+	Retries  uint64
+	Duration HistogramSnapshot
+}
+
+// MetricsSnapshot 指标快照
+type MetricsSnapshot struct {
+	Tasks map[string]TaskMetricsSnapshot
+	// Latencies 按队列统计的派发延迟, 即任务到期到开始执行的时间
+	Latencies map[string]HistogramSnapshot
+	// ActiveJobs 本实例正在执行的任务数
+	ActiveJobs int64
+}
+
+func newMetrics(durationBuckets, latencyBuckets []float64) *Metrics {
+	return &Metrics{
+		durationBuckets: normalizeBuckets(durationBuckets, DefaultDurationBuckets),
+		latencyBuckets:  normalizeBuckets(latencyBuckets, DefaultLatencyBuckets),
+		tasks:           make(map[string]*taskMetrics),
+		latencies:       make(map[string]*histogram),
+	}
+}
+
+func normalizeBuckets(buckets, defaults []float64) []float64 {
+	if len(buckets) == 0 {
+		return defaults
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return buckets
+}
+
+func (m *Metrics) taskLocked(taskID string) *taskMetrics {
+	t, ok := m.tasks[taskID]
+	if !ok {
+		t = &taskMetrics{
+			results:  make(map[string]uint64),
+			duration: newHistogram(m.durationBuckets),
+		}
+		m.tasks[taskID] = t
+	}
+	return t
+}
+
+// observeResult 记录一次执行结果与耗时
+func (m *Metrics) observeResult(result *TaskResult) {
+	outcome := result.Status.String()
+	if result.PanicError != nil {
+		outcome = "panic"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.taskLocked(result.TaskID)
+	t.results[outcome]++
+	t.duration.observe(result.Duration.Seconds())
+}
+
+func (m *Metrics) observeRetry(taskID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.taskLocked(taskID).retries++
+}
+
+// observeLatency 记录任务从到期到开始执行的延迟, 系统调度的裸任务使用调度时记录的到期时间, 没有记录时不统计
+func (m *Metrics) observeLatency(queue string, job *Job, start time.Time) {
+	due := job.ExecuteAt
+	if due.IsZero() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.latencies[queue]
+	if !ok {
+		h = newHistogram(m.latencyBuckets)
+		m.latencies[queue] = h
+	}
+	latency := start.Sub(due).Seconds()
+	if latency < 0 {
+		latency = 0
+	}
+	h.observe(latency)
+}
+
+func (m *Metrics) incActive() { atomic.AddInt64(&m.active, 1) }
+func (m *Metrics) decActive() { atomic.AddInt64(&m.active, -1) }
+
+// Snapshot 返回当前指标的副本
+func (m *Metrics) Snapshot() *MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := &MetricsSnapshot{
+		Tasks:      make(map[string]TaskMetricsSnapshot, len(m.tasks)),
+		Latencies:  make(map[string]HistogramSnapshot, len(m.latencies)),
+		ActiveJobs: atomic.LoadInt64(&m.active),
+	}
+	for id, t := range m.tasks {
+		results := make(map[string]uint64, len(t.results))
+		for k, v := range t.results {
+			results[k] = v
+		}
+		snapshot.Tasks[id] = TaskMetricsSnapshot{
+			Results:  results,
+			Retries:  t.retries,
+			Duration: t.duration.snapshot(),
+		}
+	}
+	for queue, h := range m.latencies {
+		snapshot.Latencies[queue] = h.snapshot()
+	}
+	return snapshot
+}
+
+// Metrics 返回任务管理器的指标
+func (tm *TaskManager) Metrics() *Metrics {
+	return tm.metrics
+}
+
+// QueueDepth 队列深度
+type QueueDepth struct {
+	Queue   string `json:"queue"`
+	Pending int64  `json:"pending"`
+	Delayed int64  `json:"delayed"`
+	// Parked 所属任务被暂停, 等待恢复的任务数
+	Parked int64 `json:"parked"`
+	Paused bool  `json:"paused"`
+}
+
+// QueueDepths 返回各队列待执行、延迟中与暂停中的任务数量
+func (tm *TaskManager) QueueDepths(ctx context.Context) ([]QueueDepth, error) {
+	depths := make([]QueueDepth, 0, len(tm.queues))
+	for _, q := range tm.queues {
+		pending, err := tm.broker.Len(ctx, tm.keyManager.QueueKey(q.Name))
+		if err != nil {
+			return nil, err
+		}
+		delayed, err := tm.broker.ScheduledLen(ctx, tm.keyManager.QueueDelayedKey(q.Name))
+		if err != nil {
+			return nil, err
+		}
+		parked, err := tm.parkedLen(ctx, q.Name)
+		if err != nil {
+			return nil, err
+		}
+		paused, err := tm.IsQueuePaused(ctx, q.Name)
+		if err != nil {
+			return nil, err
+		}
+		depths = append(depths, QueueDepth{Queue: q.Name, Pending: pending, Delayed: delayed, Parked: parked, Paused: paused})
+	}
+	return depths, nil
+}
+
+// MetricsHandler 返回 Prometheus 文本格式的指标接口
+func (tm *TaskManager) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		depths, err := tm.QueueDepths(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		tm.writeMetrics(w, depths)
+	})
+}
+
+func (tm *TaskManager) writeMetrics(w io.Writer, depths []QueueDepth) {
+	snapshot := tm.metrics.Snapshot()
+
+	writeHeader(w, "taskx_queue_depth", "gauge", "Number of jobs waiting in the queue.")
+	for _, d := range depths {
+		fmt.Fprintf(w, "taskx_queue_depth{queue=%s,state=\"pending\"} %d\n", quoteLabel(d.Queue), d.Pending)
+		fmt.Fprintf(w, "taskx_queue_depth{queue=%s,state=\"delayed\"} %d\n", quoteLabel(d.Queue), d.Delayed)
+		fmt.Fprintf(w, "taskx_queue_depth{queue=%s,state=\"parked\"} %d\n", quoteLabel(d.Queue), d.Parked)
+	}
+
+	writeHeader(w, "taskx_queue_paused", "gauge", "Whether the queue is paused.")
+	for _, d := range depths {
+		paused := 0
+		if d.Paused {
+			paused = 1
+		}
+		fmt.Fprintf(w, "taskx_queue_paused{queue=%s} %d\n", quoteLabel(d.Queue), paused)
+	}
+
+	writeHeader(w, "taskx_workers", "gauge", "Number of workers in this instance.")
+	fmt.Fprintf(w, "taskx_workers %d\n", len(tm.workerList()))
+	slots := 0
+	for _, worker := range tm.workerList() {
+		slots += worker.PoolSize()
+	}
+	writeHeader(w, "taskx_worker_slots", "gauge", "Total concurrency of workers in this instance.")
+	fmt.Fprintf(w, "taskx_worker_slots %d\n", slots)
+	writeHeader(w, "taskx_active_jobs", "gauge", "Number of jobs executing in this instance.")
+	fmt.Fprintf(w, "taskx_active_jobs %d\n", snapshot.ActiveJobs)
+
+	taskIDs := make([]string, 0, len(snapshot.Tasks))
+	for id := range snapshot.Tasks {
+		taskIDs = append(taskIDs, id)
+	}
+	sort.Strings(taskIDs)
+
+	writeHeader(w, "taskx_task_executions_total", "counter", "Number of task executions by outcome.")
+	for _, id := range taskIDs {
+		results := snapshot.Tasks[id].Results
+		outcomes := make([]string, 0, len(results))
+		for outcome := range results {
+			outcomes = append(outcomes, outcome)
+		}
+		sort.Strings(outcomes)
+		for _, outcome := range outcomes {
+			fmt.Fprintf(w, "taskx_task_executions_total{task=%s,outcome=%s} %d\n",
+				quoteLabel(id), quoteLabel(outcome), results[outcome])
+		}
+	}
+
+	writeHeader(w, "taskx_task_retries_total", "counter", "Number of scheduled task retries.")
+	for _, id := range taskIDs {
+		fmt.Fprintf(w, "taskx_task_retries_total{task=%s} %d\n", quoteLabel(id), snapshot.Tasks[id].Retries)
+	}
+
+	writeHeader(w, "taskx_task_duration_seconds", "histogram", "Task execution duration in seconds.")
+	for _, id := range taskIDs {
+		writeHistogram(w, "taskx_task_duration_seconds", "task="+quoteLabel(id), snapshot.Tasks[id].Duration)
+	}
+
+	queues := make([]string, 0, len(snapshot.Latencies))
+	for queue := range snapshot.Latencies {
+		queues = append(queues, queue)
+	}
+	sort.Strings(queues)
+
+	writeHeader(w, "taskx_dispatch_latency_seconds", "histogram", "Delay between a job becoming due and starting execution.")
+	for _, queue := range queues {
+		writeHistogram(w, "taskx_dispatch_latency_seconds", "queue="+quoteLabel(queue), snapshot.Latencies[queue])
+	}
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w io.Writer, name, labels string, h HistogramSnapshot) {
+	for i, bound := range h.Buckets {
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), h.Counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.Count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.Sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.Count)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
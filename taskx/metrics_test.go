@@ -0,0 +1,107 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testTracer struct {
+	ended chan string
+}
+
+func (t *testTracer) Inject(ctx context.Context, carrier map[string]string) {
+	if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+		carrier["trace-id"] = id
+	}
+}
+
+func (t *testTracer) Start(ctx context.Context, task Task, job *Job, carrier map[string]string) (context.Context, func(*TaskResult)) {
+	return ctx, func(result *TaskResult) {
+		t.ended <- carrier["trace-id"] + ":" + result.Status.String()
+	}
+}
+
+type traceIDKey struct{}
+
+func TestTaskManagerMetrics(t *testing.T) {
+	tracer := &testTracer{ended: make(chan string, 2)}
+	tm := newTestManager(t, WithTracer(tracer))
+	ctx := context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "ok"},
+		execute:        func(ctx context.Context) error { return nil },
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "bad", RetryCount: -1},
+		execute:        func(ctx context.Context) error { return errors.New("boom") },
+	}))
+
+	_, err := tm.Enqueue(ctx, "ok")
+	assert.NoError(t, err)
+	_, err = tm.Enqueue(ctx, "bad")
+	assert.NoError(t, err)
+	_, err = tm.EnqueueIn(ctx, "ok", time.Hour)
+	assert.NoError(t, err)
+
+	depths, err := tm.QueueDepths(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []QueueDepth{{Queue: DefaultQueue, Pending: 2, Delayed: 1}}, depths)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		snapshot := tm.Metrics().Snapshot()
+		return snapshot.Tasks["ok"].Results["completed"] == 1 && snapshot.Tasks["bad"].Results["failed"] == 1
+	})
+
+	snapshot := tm.Metrics().Snapshot()
+	assert.Equal(t, uint64(1), snapshot.Tasks["ok"].Duration.Count)
+	assert.Equal(t, uint64(2), snapshot.Latencies[DefaultQueue].Count)
+	assert.ElementsMatch(t, []string{"trace-1:completed", "trace-1:failed"}, []string{<-tracer.ended, <-tracer.ended})
+
+	rec := httptest.NewRecorder()
+	tm.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, `taskx_queue_depth{queue="default",state="delayed"} 1`)
+	assert.Contains(t, body, `taskx_task_executions_total{task="bad",outcome="failed"} 1`)
+	assert.Contains(t, body, `taskx_task_duration_seconds_count{task="ok"} 1`)
+	assert.Contains(t, body, `taskx_dispatch_latency_seconds_bucket{queue="default",le="+Inf"} 2`)
+	assert.Contains(t, body, "taskx_workers 1")
+}
+
+func TestTaskManagerMetricsScheduledLatency(t *testing.T) {
+	tm := newTestManager(t)
+
+	done := make(chan string, 2)
+	for _, task := range []*configTask{
+		{typ: TaskTypeContinuous, cfg: &ContinuousTaskConfig{BaseTaskConfig: BaseTaskConfig{ID: "poll"}, Interval: time.Hour}},
+		{typ: TaskTypeOnce, cfg: &OnceTaskConfig{BaseTaskConfig: BaseTaskConfig{ID: "once"}, ExecuteAt: time.Now()}},
+	} {
+		id := task.GetID()
+		task.execute = func(ctx context.Context) error {
+			done <- id
+			return nil
+		}
+		assert.NoError(t, tm.RegisterTask(task))
+	}
+
+	tm.Start()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("scheduled task not executed")
+		}
+	}
+
+	// 没有任务记录的裸任务按调度时记录的到期时间统计派发延迟
+	waitFor(t, time.Second, func() bool {
+		return tm.Metrics().Snapshot().Latencies[DefaultQueue].Count == 2
+	})
+	assert.Less(t, tm.Metrics().Snapshot().Latencies[DefaultQueue].Sum, 10.0)
+}
@@ -0,0 +1,132 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// Handler 执行一次任务, 是中间件链的基本单元
+type Handler func(ctx context.Context, task Task, job *Job) error
+
+// Middleware 包装 Handler, 可以替换 ctx、改变控制流或处理返回的错误
+// 通过 WithMiddleware 注册, 先注册的位于外层
+type Middleware func(next Handler) Handler
+
+func chainMiddlewares(middlewares []Middleware, h Handler) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// PanicError 由 Recovery 中间件把 panic 转换成的错误, 任务结果仍会记录为 panic 并触发 OnTaskPanic
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// timeoutError 执行超时, 与 ErrTaskTimeout 匹配, 同时保留任务返回的原始错误
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrTaskTimeout, e.err)
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTaskTimeout
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+type timeoutContextKey struct{}
+
+// execute 中间件链最内层的 Handler, 在超时控制下调用任务
+func (tm *TaskManager) execute(ctx context.Context, task Task, job *Job) error {
+	timeout := baseConfigOf(task.GetConfig()).Timeout
+	if override, ok := ctx.Value(timeoutContextKey{}).(time.Duration); ok {
+		timeout = override
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var err error
+	if pt, ok := task.(PayloadTask); ok {
+		payload, _ := PayloadFromContext(ctx)
+		err = pt.ExecuteWithPayload(ctx, payload)
+	} else {
+		err = task.Execute(ctx)
+	}
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &timeoutError{err: err}
+	}
+	return err
+}
+
+// Recovery 在中间件链内捕获 panic 并转换为 *PanicError, 外层中间件可以像普通错误一样观察到它
+// 未使用时 panic 由 Worker 在链外捕获, 外层中间件会被直接跳过
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task, job *Job) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+			return next(ctx, task, job)
+		}
+	}
+}
+
+// Timeout 覆盖任务配置的执行超时, timeout 为 0 时不限制
+// 需要按任务区分时可以在自定义中间件中根据 task 选择是否调用
+func Timeout(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task, job *Job) error {
+			return next(context.WithValue(ctx, timeoutContextKey{}, timeout), task, job)
+		}
+	}
+}
+
+// Logger 日志输出, *log.Logger 满足该接口
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Logging 记录每次执行的开始、结束与耗时, logger 为空时使用标准库默认 Logger
+func Logging(logger Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, task Task, job *Job) error {
+			start := time.Now()
+			logger.Printf("taskx: task=%s job=%s attempt=%d started", task.GetID(), job.ID, job.Retried+1)
+
+			err := next(ctx, task, job)
+			if err != nil {
+				logger.Printf("taskx: task=%s job=%s attempt=%d failed in %s: %v",
+					task.GetID(), job.ID, job.Retried+1, time.Since(start), err)
+			} else {
+				logger.Printf("taskx: task=%s job=%s attempt=%d completed in %s",
+					task.GetID(), job.ID, job.Retried+1, time.Since(start))
+			}
+			return err
+		}
+	}
+}
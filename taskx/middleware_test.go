@@ -0,0 +1,142 @@
+package taskx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantContextKey struct{}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestChainMiddlewares(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, task Task, job *Job) error {
+				calls = append(calls, name+">")
+				err := next(ctx, task, job)
+				calls = append(calls, "<"+name)
+				return err
+			}
+		}
+	}
+	h := chainMiddlewares([]Middleware{trace("a"), trace("b")}, func(ctx context.Context, task Task, job *Job) error {
+		calls = append(calls, "task")
+		return nil
+	})
+	assert.NoError(t, h(context.Background(), nil, nil))
+	assert.Equal(t, []string{"a>", "b>", "task", "<b", "<a"}, calls)
+}
+
+func TestTaskManagerMiddleware(t *testing.T) {
+	logs := &syncBuffer{}
+	var (
+		mu      sync.Mutex
+		tenants []string
+		errs    = make(map[string]error)
+	)
+	tenant := func(next Handler) Handler {
+		return func(ctx context.Context, task Task, job *Job) error {
+			ctx = context.WithValue(ctx, tenantContextKey{}, "acme")
+			err := next(ctx, task, job)
+			mu.Lock()
+			errs[task.GetID()] = err
+			mu.Unlock()
+			return err
+		}
+	}
+	tm := newTestManager(t, WithMiddleware(
+		Logging(log.New(logs, "", 0)),
+		tenant,
+		Recovery(),
+		Timeout(50*time.Millisecond),
+	))
+	ctx := context.Background()
+
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "tenant", RetryCount: -1},
+		execute: func(ctx context.Context) error {
+			mu.Lock()
+			tenants = append(tenants, fmt.Sprint(ctx.Value(tenantContextKey{})))
+			mu.Unlock()
+			return nil
+		},
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "panic", RetryCount: -1},
+		execute: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}))
+	// Timeout 中间件覆盖任务配置的超时
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "slow", RetryCount: -1, Timeout: time.Minute},
+		execute: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+
+	tm.Start()
+	jobs := make(map[string]*Job)
+	for _, id := range []string{"tenant", "panic", "slow"} {
+		job, err := tm.Enqueue(ctx, id)
+		assert.NoError(t, err)
+		jobs[id] = job
+	}
+	statusOf := func(id string) TaskStatus {
+		status, err := tm.GetStatus(ctx, jobs[id].ID)
+		if err != nil {
+			return TaskStatusPending
+		}
+		return status.Status
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		return statusOf("tenant") == TaskStatusCompleted &&
+			statusOf("panic") == TaskStatusFailed &&
+			statusOf("slow") == TaskStatusTimeout
+	})
+
+	mu.Lock()
+	assert.Equal(t, []string{"acme"}, tenants)
+	var panicErr *PanicError
+	assert.True(t, errors.As(errs["panic"], &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.ErrorIs(t, errs["slow"], ErrTaskTimeout)
+	assert.ErrorIs(t, errs["slow"], context.DeadlineExceeded)
+	mu.Unlock()
+
+	// Recovery 捕获的 panic 仍按 panic 记录
+	status, err := tm.GetStatus(ctx, jobs["panic"].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "boom", status.Result.PanicError)
+
+	output := logs.String()
+	assert.Contains(t, output, "task=tenant job="+jobs["tenant"].ID+" attempt=1 completed")
+	assert.Contains(t, output, "task=panic job="+jobs["panic"].ID+" attempt=1 failed")
+	assert.Contains(t, output, "panic: boom")
+}
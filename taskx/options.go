@@ -1,10 +1,45 @@
 package taskx
 
+import "time"
+
 type Options struct {
 	Namespace  string
 	WorkerSize int
 	PoolSize   int
 	Hooks      []TaskHook
+	// 任务未配置 Backoff 时使用的重试间隔策略
+	RetryBackoff Backoff
+	// 任务状态的保留时间, 每次状态变更时刷新
+	StatusTTL time.Duration
+	// 任务锁的有效期, 执行期间每隔 LockTTL/3 续期
+	LockTTL time.Duration
+	// 任务参数的编解码器
+	Codec Codec
+	// Queues 具名队列, 未包含默认队列时自动补充
+	Queues []Queue
+	// StrictPriority 为 true 时严格按优先级领取, 否则按优先级加权轮询
+	StrictPriority bool
+	// TagConcurrency 各标签在集群内的最大并发数量
+	TagConcurrency map[string]int
+	// LeaderLeaseTTL 调度 Leader 租约的有效期, Leader 宕机后最长经过该时间由其他实例接任
+	LeaderLeaseTTL time.Duration
+	// PublishProgress 为 true 时通过 pub/sub 广播任务进度
+	PublishProgress bool
+	// DurationBuckets 与 LatencyBuckets 为执行耗时与派发延迟直方图的分桶, 单位秒
+	DurationBuckets []float64
+	LatencyBuckets  []float64
+	// Tracer 链路追踪实现, 为空时不追踪
+	Tracer Tracer
+	// Autoscale 自动伸缩配置, 为空时 Worker 数量与协程池大小固定
+	Autoscale *AutoscaleConfig
+	// BlockingDispatch 为 true 时在队列上阻塞等待新任务, 任务入队后立即派发
+	BlockingDispatch bool
+	// DispatchInterval 轮询派发的间隔, 阻塞派发时为单次阻塞的超时即兜底轮询间隔, 默认 1 秒
+	DispatchInterval time.Duration
+	// Middlewares 包装每次执行的中间件, 先注册的位于外层
+	Middlewares []Middleware
+	// WorkflowTTL 运行中工作流状态的保留时间, 每次更新时刷新, 默认 7 天
+	WorkflowTTL time.Duration
 }
 
 func DefaultOptions() Options {
@@ -13,6 +48,16 @@ func DefaultOptions() Options {
 		WorkerSize: defaultWorkerSize,
 		PoolSize:   defaultWorkerPool,
 		Hooks:      []TaskHook{&NoopTaskHook{}},
+		RetryBackoff: &ExponentialBackoff{
+			Base: time.Millisecond * defaultRetryDelay,
+			Max:  time.Second * defaultRetryMaxDelay,
+		},
+		StatusTTL:        time.Second * defaultStatusTTL,
+		LockTTL:          time.Second * defaultLockTimeout,
+		Codec:            JSONCodec{},
+		LeaderLeaseTTL:   time.Second * defaultLeaderLease,
+		DispatchInterval: time.Second * defaultDispatchInterval,
+		WorkflowTTL:      time.Second * defaultWorkflowTTL,
 	}
 }
 
@@ -41,3 +86,109 @@ func WithHooks(hooks ...TaskHook) Option {
 		o.Hooks = hooks
 	}
 }
+
+func WithRetryBackoff(backoff Backoff) Option {
+	return func(o *Options) {
+		o.RetryBackoff = backoff
+	}
+}
+
+func WithStatusTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.StatusTTL = ttl
+	}
+}
+
+// WithWorkflowTTL 设置运行中工作流状态的保留时间, 需大于工作流的预期执行时长
+func WithWorkflowTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.WorkflowTTL = ttl
+	}
+}
+
+func WithLockTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.LockTTL = ttl
+	}
+}
+
+func WithCodec(codec Codec) Option {
+	return func(o *Options) {
+		o.Codec = codec
+	}
+}
+
+func WithQueues(queues ...Queue) Option {
+	return func(o *Options) {
+		o.Queues = queues
+	}
+}
+
+func WithStrictPriority(strict bool) Option {
+	return func(o *Options) {
+		o.StrictPriority = strict
+	}
+}
+
+func WithLeaderLeaseTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.LeaderLeaseTTL = ttl
+	}
+}
+
+func WithProgressPublish(enabled bool) Option {
+	return func(o *Options) {
+		o.PublishProgress = enabled
+	}
+}
+
+// WithMetricsBuckets 自定义执行耗时与派发延迟直方图的分桶, 传入空切片时使用默认值
+func WithMetricsBuckets(duration, latency []float64) Option {
+	return func(o *Options) {
+		o.DurationBuckets = duration
+		o.LatencyBuckets = latency
+	}
+}
+
+func WithTracer(tracer Tracer) Option {
+	return func(o *Options) {
+		o.Tracer = tracer
+	}
+}
+
+// WithAutoscale 开启自动伸缩, WorkerSize 与 PoolSize 作为初始值
+func WithAutoscale(config AutoscaleConfig) Option {
+	return func(o *Options) {
+		o.Autoscale = &config
+	}
+}
+
+// WithBlockingDispatch 开启阻塞派发, 每个队列占用一个阻塞连接
+func WithBlockingDispatch(enabled bool) Option {
+	return func(o *Options) {
+		o.BlockingDispatch = enabled
+	}
+}
+
+func WithDispatchInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.DispatchInterval = interval
+	}
+}
+
+// WithMiddleware 追加执行中间件, 可多次调用, 按注册顺序由外向内包装每次执行
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(o *Options) {
+		o.Middlewares = append(o.Middlewares, middlewares...)
+	}
+}
+
+// WithTagConcurrency 限制带有 tag 标签的任务在集群内的并发数量, 可多次调用配置多个标签
+func WithTagConcurrency(tag string, limit int) Option {
+	return func(o *Options) {
+		if o.TagConcurrency == nil {
+			o.TagConcurrency = make(map[string]int)
+		}
+		o.TagConcurrency[tag] = limit
+	}
+}
@@ -0,0 +1,154 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// PauseQueue 暂停队列, 集群内所有实例停止从该队列领取任务, 已排队的任务保持不变
+// 暂停状态保存在 Broker 中, 直到 ResumeQueue 才会解除
+func (tm *TaskManager) PauseQueue(ctx context.Context, queue string) error {
+	if !tm.hasQueue(queue) {
+		return ErrQueueNotFound
+	}
+	return tm.broker.Set(ctx, tm.keyManager.QueuePausedKey(queue), strconv.FormatInt(time.Now().Unix(), 10), 0)
+}
+
+// ResumeQueue 恢复队列
+func (tm *TaskManager) ResumeQueue(ctx context.Context, queue string) error {
+	if !tm.hasQueue(queue) {
+		return ErrQueueNotFound
+	}
+	return tm.broker.Del(ctx, tm.keyManager.QueuePausedKey(queue))
+}
+
+// IsQueuePaused 返回队列是否被暂停
+func (tm *TaskManager) IsQueuePaused(ctx context.Context, queue string) (bool, error) {
+	return tm.isPaused(ctx, tm.keyManager.QueuePausedKey(queue))
+}
+
+// PauseTask 暂停任务, 集群内所有实例不再执行该任务, 包括定时、持续任务的触发
+// 领取到的任务按原顺序移入任务的暂停列表, 恢复时放回队列; 暂停期间定时任务的触发直接丢弃, 不会补发
+func (tm *TaskManager) PauseTask(ctx context.Context, taskID string) error {
+	if !tm.hasTask(taskID) {
+		return ErrTaskNotFound
+	}
+	return tm.broker.Set(ctx, tm.keyManager.TaskPausedKey(taskID), strconv.FormatInt(time.Now().Unix(), 10), 0)
+}
+
+// ResumeTask 恢复任务, 暂停期间领取到的任务按原顺序放回各自的队列
+func (tm *TaskManager) ResumeTask(ctx context.Context, taskID string) error {
+	if !tm.hasTask(taskID) {
+		return ErrTaskNotFound
+	}
+	if err := tm.broker.Del(ctx, tm.keyManager.TaskPausedKey(taskID)); err != nil {
+		return err
+	}
+	return tm.restoreParked(ctx, taskID)
+}
+
+// IsTaskPaused 返回任务是否被暂停
+func (tm *TaskManager) IsTaskPaused(ctx context.Context, taskID string) (bool, error) {
+	return tm.isPaused(ctx, tm.keyManager.TaskPausedKey(taskID))
+}
+
+func (tm *TaskManager) hasTask(taskID string) bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	_, exists := tm.tasks[taskID]
+	return exists
+}
+
+// parkJob 把暂停任务的条目从 Worker 的处理列表移入任务的暂停列表
+func (tm *TaskManager) parkJob(ctx context.Context, workerID, queue, entry string, task Task) {
+	processing := tm.keyManager.QueueProcessingKey(workerID, queue)
+	if entry == task.GetID() && task.GetType() == TaskTypeSchedule {
+		tm.broker.Ack(ctx, processing, entry)
+		return
+	}
+	err := handOff(ctx, tm.broker, processing, entry, func() error {
+		return tm.broker.Push(ctx, tm.keyManager.TaskParkedKey(task.GetID(), queue), entry)
+	})
+	if err != nil {
+		return
+	}
+
+	// 派发时读取的暂停状态可能已过期, 任务已恢复时立即放回, 避免条目滞留在暂停列表中
+	if paused, err := tm.IsTaskPaused(ctx, task.GetID()); err == nil && !paused {
+		tm.restoreParked(ctx, task.GetID())
+	}
+}
+
+// restoreParked 把任务暂停列表中的条目按原顺序放回各自的队列
+func (tm *TaskManager) restoreParked(ctx context.Context, taskID string) error {
+	for _, q := range tm.queues {
+		_, err := tm.broker.RequeueAll(ctx, tm.keyManager.TaskParkedKey(taskID, q.Name), tm.keyManager.QueueKey(q.Name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parkedLen 返回 queue 中被暂停任务的数量
+func (tm *TaskManager) parkedLen(ctx context.Context, queue string) (int64, error) {
+	tm.mu.RLock()
+	taskIDs := make([]string, 0, len(tm.tasks))
+	for id := range tm.tasks {
+		taskIDs = append(taskIDs, id)
+	}
+	tm.mu.RUnlock()
+
+	var total int64
+	for _, id := range taskIDs {
+		n, err := tm.broker.Len(ctx, tm.keyManager.TaskParkedKey(id, queue))
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (tm *TaskManager) isPaused(ctx context.Context, key string) (bool, error) {
+	_, err := tm.broker.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// pauseState 一轮派发内读取到的暂停状态, 任务的状态按需读取并缓存
+// 读取失败时视为未暂停, 避免 Broker 抖动导致任务停摆
+type pauseState struct {
+	tm     *TaskManager
+	queues map[string]bool
+	tasks  map[string]bool
+}
+
+func (tm *TaskManager) loadPauseState() *pauseState {
+	state := &pauseState{
+		tm:     tm,
+		queues: make(map[string]bool, len(tm.queues)),
+		tasks:  make(map[string]bool),
+	}
+	for _, q := range tm.queues {
+		state.queues[q.Name], _ = tm.IsQueuePaused(tm.ctx, q.Name)
+	}
+	return state
+}
+
+func (s *pauseState) queuePaused(queue string) bool {
+	return s.queues[queue]
+}
+
+func (s *pauseState) taskPaused(taskID string) bool {
+	paused, ok := s.tasks[taskID]
+	if !ok {
+		paused, _ = s.tm.IsTaskPaused(s.tm.ctx, taskID)
+		s.tasks[taskID] = paused
+	}
+	return paused
+}
@@ -0,0 +1,118 @@
+package taskx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskManagerPause(t *testing.T) {
+	tm := newTestManager(t, WithQueues(Queue{Name: "mail", Priority: 1}))
+	ctx := context.Background()
+
+	var mails, reports int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "mail", Queue: "mail"},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&mails, 1)
+			return nil
+		},
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "report"},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&reports, 1)
+			return nil
+		},
+	}))
+
+	assert.ErrorIs(t, tm.PauseQueue(ctx, "missing"), ErrQueueNotFound)
+	assert.ErrorIs(t, tm.PauseTask(ctx, "missing"), ErrTaskNotFound)
+	assert.NoError(t, tm.PauseQueue(ctx, "mail"))
+	assert.NoError(t, tm.PauseTask(ctx, "report"))
+	paused, err := tm.IsQueuePaused(ctx, "mail")
+	assert.NoError(t, err)
+	assert.True(t, paused)
+
+	mail, err := tm.Enqueue(ctx, "mail")
+	assert.NoError(t, err)
+	report, err := tm.Enqueue(ctx, "report")
+	assert.NoError(t, err)
+
+	tm.Start()
+	time.Sleep(2500 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&mails))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&reports))
+
+	// 暂停的队列不领取, 任务原样留在队列中
+	members, err := tm.broker.Peek(ctx, tm.keyManager.QueueKey("mail"), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{mail.ID}, members)
+
+	assert.NoError(t, tm.ResumeQueue(ctx, "mail"))
+	assert.NoError(t, tm.ResumeTask(ctx, "report"))
+	waitFor(t, 5*time.Second, func() bool {
+		return atomic.LoadInt32(&mails) == 1 && atomic.LoadInt32(&reports) == 1
+	})
+	status, err := tm.GetStatus(ctx, report.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, status.Attempt)
+}
+
+func TestTaskManagerPauseTaskParksJobs(t *testing.T) {
+	broker := &countingBroker{Broker: NewMemoryBroker()}
+	// 单个执行名额与阻塞派发保证按队列顺序逐个执行
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1), WithPoolSize(1), WithBlockingDispatch(true))
+	t.Cleanup(tm.Stop)
+	ctx := context.Background()
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "report"},
+		execute: func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, ProgressFromContext(ctx).job.ID)
+			mu.Unlock()
+			return nil
+		},
+	}))
+	assert.NoError(t, tm.PauseTask(ctx, "report"))
+
+	var enqueued []string
+	for i := 0; i < 20; i++ {
+		job, err := tm.Enqueue(ctx, "report")
+		assert.NoError(t, err)
+		enqueued = append(enqueued, job.ID)
+	}
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		depths, err := tm.QueueDepths(ctx)
+		return err == nil && depths[0].Parked == 20
+	})
+	depths, err := tm.QueueDepths(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []QueueDepth{{Queue: DefaultQueue, Parked: 20}}, depths)
+
+	// 暂停列表中的任务不会被反复领取
+	before := atomic.LoadInt64(&broker.calls)
+	time.Sleep(1500 * time.Millisecond)
+	assert.Less(t, atomic.LoadInt64(&broker.calls)-before, int64(20))
+
+	assert.NoError(t, tm.ResumeTask(ctx, "report"))
+	waitFor(t, 5*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == len(enqueued)
+	})
+	mu.Lock()
+	assert.Equal(t, enqueued, order)
+	mu.Unlock()
+}
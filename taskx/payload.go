@@ -0,0 +1,118 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Codec 负责任务参数的编解码
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec 默认的 JSON 编解码器
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Payload 随任务投递的参数
+type Payload struct {
+	data  []byte
+	codec Codec
+}
+
+// Bytes 返回编码后的原始数据
+func (p Payload) Bytes() []byte {
+	return p.data
+}
+
+// Empty 判断是否携带了参数
+func (p Payload) Empty() bool {
+	return len(p.data) == 0
+}
+
+// Decode 使用 TaskManager 配置的编解码器解码参数
+func (p Payload) Decode(v interface{}) error {
+	codec := p.codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return codec.Unmarshal(p.data, v)
+}
+
+// PayloadTask 可选接口, 任务实现后由 ExecuteWithPayload 代替 Execute 接收参数
+type PayloadTask interface {
+	Task
+	ExecuteWithPayload(ctx context.Context, payload Payload) error
+}
+
+type payloadContextKey struct{}
+
+// PayloadFromContext 从 Execute 的 ctx 中取出任务参数
+func PayloadFromContext(ctx context.Context) (Payload, bool) {
+	p, ok := ctx.Value(payloadContextKey{}).(Payload)
+	return p, ok
+}
+
+func withPayload(ctx context.Context, p Payload) context.Context {
+	return context.WithValue(ctx, payloadContextKey{}, p)
+}
+
+type resultContextKey struct{}
+
+// resultHolder 保存任务执行期间通过 SetResult 设置的结果
+type resultHolder struct {
+	mu    sync.Mutex
+	data  []byte
+	codec Codec
+}
+
+func (h *resultHolder) bytes() []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.data
+}
+
+func withResultHolder(ctx context.Context, h *resultHolder) context.Context {
+	return context.WithValue(ctx, resultContextKey{}, h)
+}
+
+// SetResult 在 Execute 中设置任务结果, 结果使用 TaskManager 的编解码器编码
+// 工作流中的结果会传递给下游节点; 多次调用时以最后一次为准, 不在任务上下文中调用时忽略
+func SetResult(ctx context.Context, v interface{}) error {
+	h, ok := ctx.Value(resultContextKey{}).(*resultHolder)
+	if !ok {
+		return nil
+	}
+
+	data, err := h.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	h.data = data
+	h.mu.Unlock()
+	return nil
+}
+
+// WithPayload 使用 TaskManager 的编解码器编码任务参数
+func WithPayload(v interface{}) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.Payload = v
+	}
+}
+
+// WithRawPayload 直接使用已编码的任务参数
+func WithRawPayload(data []byte) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.RawPayload = data
+	}
+}
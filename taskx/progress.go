@@ -0,0 +1,113 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Progress 任务执行进度
+type Progress struct {
+	JobID   string  `json:"job_id"`
+	TaskID  string  `json:"task_id"`
+	Percent float64 `json:"percent"`
+	Message string  `json:"message,omitempty"`
+	// Fields 自定义字段, 使用 JSON 编码保存
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// ProgressReporter 在 Execute 中上报进度, 通过 ProgressFromContext 获取
+type ProgressReporter struct {
+	tm   *TaskManager
+	task Task
+	job  *Job
+}
+
+type progressContextKey struct{}
+
+// ProgressFromContext 从 Execute 的 ctx 中取出进度上报器, 不在任务上下文中时返回 nil, 调用 nil 的 Report 不会生效
+func ProgressFromContext(ctx context.Context) *ProgressReporter {
+	r, _ := ctx.Value(progressContextKey{}).(*ProgressReporter)
+	return r
+}
+
+func withProgressReporter(ctx context.Context, r *ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressContextKey{}, r)
+}
+
+// Report 上报进度, percent 取值 0-100, 超出范围时截断
+// 进度写入任务状态, 开启 WithProgressPublish 时同时广播, 并触发 ProgressHook
+func (r *ProgressReporter) Report(ctx context.Context, percent float64, message string, fields map[string]interface{}) error {
+	if r == nil {
+		return nil
+	}
+
+	switch {
+	case percent < 0:
+		percent = 0
+	case percent > 100:
+		percent = 100
+	}
+	progress := &Progress{
+		JobID:     r.job.ID,
+		TaskID:    r.task.GetID(),
+		Percent:   percent,
+		Message:   message,
+		Fields:    fields,
+		UpdatedAt: time.Now(),
+	}
+	return r.tm.reportProgress(ctx, r.task, progress)
+}
+
+func (tm *TaskManager) reportProgress(ctx context.Context, task Task, progress *Progress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+
+	err = tm.broker.SetFields(ctx, tm.keyManager.TaskStatusKey(progress.JobID),
+		map[string]string{statusFieldProgress: string(data)}, tm.statusTTL)
+	if err != nil {
+		return err
+	}
+	if tm.publishProgress {
+		if err := tm.broker.Publish(ctx, tm.keyManager.ProgressChannel(), string(data)); err != nil {
+			return err
+		}
+	}
+
+	tm.triggerHooks(func(h TaskHook) error {
+		if ph, ok := h.(ProgressHook); ok {
+			return ph.OnTaskProgress(task, progress)
+		}
+		return nil
+	})
+	return nil
+}
+
+// SubscribeProgress 订阅集群内所有任务的进度, 需要开启 WithProgressPublish
+// 返回的 channel 在 ctx 结束或订阅断开时关闭
+func (tm *TaskManager) SubscribeProgress(ctx context.Context) (<-chan *Progress, error) {
+	messages, err := tm.broker.Subscribe(ctx, tm.keyManager.ProgressChannel())
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *Progress)
+	go func() {
+		defer close(out)
+		for msg := range messages {
+			progress := &Progress{}
+			if err := json.Unmarshal([]byte(msg), progress); err != nil {
+				continue
+			}
+			select {
+			case out <- progress:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
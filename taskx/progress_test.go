@@ -0,0 +1,78 @@
+package taskx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type progressHook struct {
+	NoopTaskHook
+	mu      sync.Mutex
+	updates []*Progress
+}
+
+func (h *progressHook) OnTaskProgress(task Task, progress *Progress) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.updates = append(h.updates, progress)
+	return nil
+}
+
+func TestTaskManagerProgress(t *testing.T) {
+	ctx := context.Background()
+	hook := &progressHook{}
+	tm := NewTaskManagerWithBroker(NewMemoryBroker(), WithWorkerSize(1), WithHooks(hook), WithProgressPublish(true))
+	t.Cleanup(tm.Stop)
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	updates, err := tm.SubscribeProgress(subCtx)
+	assert.NoError(t, err)
+
+	task := &testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "import"},
+		execute: func(ctx context.Context) error {
+			reporter := ProgressFromContext(ctx)
+			if err := reporter.Report(ctx, 50, "half", map[string]interface{}{"rows": 10}); err != nil {
+				return err
+			}
+			return reporter.Report(ctx, 150, "done", nil)
+		},
+	}
+	assert.NoError(t, tm.RegisterTask(task))
+	job, err := tm.Enqueue(ctx, "import")
+	assert.NoError(t, err)
+	tm.Start()
+
+	select {
+	case progress := <-updates:
+		assert.Equal(t, job.ID, progress.JobID)
+		assert.Equal(t, "import", progress.TaskID)
+		assert.Equal(t, float64(50), progress.Percent)
+		assert.Equal(t, float64(10), progress.Fields["rows"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("progress was not published")
+	}
+
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusCompleted
+	})
+	status, err := tm.GetStatus(ctx, job.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, status.Progress) {
+		assert.Equal(t, float64(100), status.Progress.Percent)
+		assert.Equal(t, "done", status.Progress.Message)
+	}
+
+	hook.mu.Lock()
+	assert.Len(t, hook.updates, 2)
+	hook.mu.Unlock()
+
+	// 不在任务上下文中时上报不生效
+	assert.NoError(t, ProgressFromContext(ctx).Report(ctx, 10, "", nil))
+}
@@ -0,0 +1,134 @@
+package taskx
+
+import (
+	"sort"
+)
+
+// Queue 描述一个具名队列
+type Queue struct {
+	Name string
+	// Priority 严格优先级模式下数值大的队列优先, 加权模式下作为权重, 小于 1 时视为 1
+	Priority int
+	// Workers 专属于该队列的 Worker 数量, 这些 Worker 只处理该队列的任务
+	// 共享 Worker(WorkerSize) 按调度策略处理所有队列
+	Workers int
+	// RateLimit 队列内所有任务共享的执行频率限制
+	RateLimit *RateLimit
+}
+
+func (q Queue) weight() int {
+	if q.Priority < 1 {
+		return 1
+	}
+	return q.Priority
+}
+
+// normalizeQueues 去重并补齐默认队列, 按优先级从高到低排序
+func normalizeQueues(queues []Queue) []Queue {
+	seen := make(map[string]bool, len(queues)+1)
+	result := make([]Queue, 0, len(queues)+1)
+	for _, q := range queues {
+		if q.Name == "" || seen[q.Name] {
+			continue
+		}
+		seen[q.Name] = true
+		result = append(result, q)
+	}
+	if !seen[DefaultQueue] {
+		result = append(result, Queue{Name: DefaultQueue, Priority: 1})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].weight() > result[j].weight()
+	})
+	return result
+}
+
+// queueSelector 决定 Worker 每次领取任务时尝试各队列的顺序, 仅由调度协程使用
+type queueSelector struct {
+	queues  []Queue
+	strict  bool
+	current []int
+}
+
+func newQueueSelector(queues []Queue, strict bool) *queueSelector {
+	return &queueSelector{
+		queues:  queues,
+		strict:  strict,
+		current: make([]int, len(queues)),
+	}
+}
+
+// order 返回本次领取的队列顺序
+// 严格模式始终按优先级从高到低; 加权模式用平滑加权轮询选出首选队列, 其余按优先级作为后备,
+// 首选队列为空时不会让 Worker 空闲
+func (s *queueSelector) order() []string {
+	names := make([]string, 0, len(s.queues))
+	if s.strict || len(s.queues) == 1 {
+		for _, q := range s.queues {
+			names = append(names, q.Name)
+		}
+		return names
+	}
+
+	total, best := 0, 0
+	for i, q := range s.queues {
+		s.current[i] += q.weight()
+		total += q.weight()
+		if s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	s.current[best] -= total
+
+	names = append(names, s.queues[best].Name)
+	for i, q := range s.queues {
+		if i != best {
+			names = append(names, q.Name)
+		}
+	}
+	return names
+}
+
+// has 判断是否会从该队列领取
+func (s *queueSelector) has(name string) bool {
+	for _, q := range s.queues {
+		if q.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasQueue 判断队列是否已配置
+func (tm *TaskManager) hasQueue(name string) bool {
+	for _, q := range tm.queues {
+		if q.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// taskQueue 返回任务配置的默认队列
+func (tm *TaskManager) taskQueue(task Task) string {
+	if queue := baseConfigOf(task.GetConfig()).Queue; queue != "" {
+		return queue
+	}
+	return DefaultQueue
+}
+
+// jobQueue 返回任务所在的队列, 系统调度的裸任务ID没有记录, 使用任务配置的队列
+func (tm *TaskManager) jobQueue(job *Job) string {
+	if job.Queue != "" {
+		return job.Queue
+	}
+
+	tm.mu.RLock()
+	task, exists := tm.tasks[job.TaskID]
+	tm.mu.RUnlock()
+	if !exists {
+		return DefaultQueue
+	}
+	return tm.taskQueue(task)
+}
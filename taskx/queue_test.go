@@ -0,0 +1,82 @@
+package taskx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueSelector(t *testing.T) {
+	queues := normalizeQueues([]Queue{{Name: "low", Priority: 1}, {Name: "critical", Priority: 6}, {Name: "critical", Priority: 1}})
+	assert.Equal(t, []string{"critical", "low", DefaultQueue}, []string{queues[0].Name, queues[1].Name, queues[2].Name})
+
+	strict := newQueueSelector(queues, true)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []string{"critical", "low", DefaultQueue}, strict.order())
+	}
+
+	// 权重 6:1:1, 每 8 次领取中首选队列的分布与权重一致
+	weighted := newQueueSelector(queues, false)
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		order := weighted.order()
+		assert.Len(t, order, 3)
+		counts[order[0]]++
+	}
+	assert.Equal(t, map[string]int{"critical": 6, "low": 1, DefaultQueue: 1}, counts)
+}
+
+func TestTaskManagerPriorityQueues(t *testing.T) {
+	tm := newTestManager(t,
+		WithWorkerSize(1), WithPoolSize(1),
+		WithQueues(Queue{Name: "critical", Priority: 10}, Queue{Name: "low", Priority: 1}),
+		WithStrictPriority(true),
+	)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var order []string
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "batch", Queue: "low"},
+		execute: func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, "low")
+			mu.Unlock()
+			return nil
+		},
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "alert"},
+		execute: func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, "critical")
+			mu.Unlock()
+			return nil
+		},
+	}))
+	assert.ErrorIs(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "orphan", Queue: "missing"},
+	}), ErrQueueNotFound)
+
+	_, err := tm.Enqueue(ctx, "alert", WithQueue("missing"))
+	assert.ErrorIs(t, err, ErrQueueNotFound)
+
+	for i := 0; i < 2; i++ {
+		_, err := tm.Enqueue(ctx, "batch")
+		assert.NoError(t, err)
+	}
+	job, err := tm.Enqueue(ctx, "alert", WithQueue("critical"))
+	assert.NoError(t, err)
+	assert.Equal(t, "critical", job.Queue)
+
+	tm.Start()
+	waitFor(t, 10*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(order) == 3
+	})
+	assert.Equal(t, []string{"critical", "low", "low"}, order)
+}
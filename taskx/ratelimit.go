@@ -0,0 +1,57 @@
+package taskx
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitAlgorithm 限流算法
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket 令牌桶, 容量为 Limit, 每个 Window 匀速补充 Limit 个令牌, 允许短时突发
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow 滑动窗口, 任意 Window 时长内最多放行 Limit 次
+	SlidingWindow
+)
+
+// RateLimit 描述每个时间窗口内允许执行的次数, 通过 Broker 在集群内共享
+type RateLimit struct {
+	Limit     int
+	Window    time.Duration
+	Algorithm RateLimitAlgorithm
+}
+
+func (l *RateLimit) valid() bool {
+	return l != nil && l.Limit > 0 && l.Window >= time.Millisecond
+}
+
+// throttle 在派发前检查任务与队列的限流, 返回需要延后的时间, 0 表示放行
+// 任务限流通过而队列限流未通过时, 已消耗的任务配额不会退还
+func (tm *TaskManager) throttle(ctx context.Context, d delivery) time.Duration {
+	if limit := baseConfigOf(d.task.GetConfig()).RateLimit; limit.valid() {
+		wait, err := tm.broker.Allow(ctx, tm.keyManager.TaskRateLimitKey(d.task.GetID()), limit, time.Now())
+		if err == nil && wait > 0 {
+			return wait
+		}
+	}
+
+	for _, q := range tm.queues {
+		if q.Name != d.job.Queue || !q.RateLimit.valid() {
+			continue
+		}
+		wait, err := tm.broker.Allow(ctx, tm.keyManager.QueueRateLimitKey(q.Name), q.RateLimit, time.Now())
+		if err == nil && wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
+
+// deferJob 把被限流的任务从处理列表移回所属队列的延迟集合, 不计入重试次数
+func (tm *TaskManager) deferJob(ctx context.Context, workerID, entry string, job *Job, delay time.Duration) {
+	handOff(ctx, tm.broker, tm.keyManager.QueueProcessingKey(workerID, job.Queue), entry, func() error {
+		return tm.broker.ScheduleIfAbsent(ctx, tm.keyManager.QueueDelayedKey(job.Queue), entry, time.Now().Add(delay))
+	})
+}
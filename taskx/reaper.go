@@ -0,0 +1,47 @@
+package taskx
+
+import (
+	"time"
+)
+
+// reaper 周期性地回收心跳过期 Worker 的处理列表, 把未确认的任务放回队列
+// 由 Leader 回收集群内所有实例的 Worker, 回收操作本身是原子的
+func (tm *TaskManager) reaper() {
+	ticker := time.NewTicker(time.Second * defaultReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tm.ctx.Done():
+			return
+		case <-ticker.C:
+			if tm.IsLeader() {
+				tm.reapExpiredWorkers(time.Now())
+			}
+		}
+	}
+}
+
+func (tm *TaskManager) reapExpiredWorkers(now time.Time) {
+	registryKey := tm.keyManager.WorkerRegistryKey()
+	expired, err := tm.broker.DueMembers(tm.ctx, registryKey,
+		now.Add(-time.Second*defaultHeartbeatTTL), defaultReapBatch)
+	if err != nil {
+		return
+	}
+
+	// 每个队列都有独立的处理列表, 集群内各实例需配置相同的队列
+	for _, workerID := range expired {
+		reaped := true
+		for _, q := range tm.queues {
+			_, err := tm.broker.RequeueAll(tm.ctx,
+				tm.keyManager.QueueProcessingKey(workerID, q.Name), tm.keyManager.QueueKey(q.Name))
+			if err != nil {
+				reaped = false
+			}
+		}
+		if reaped {
+			tm.broker.Unschedule(tm.ctx, registryKey, workerID)
+		}
+	}
+}
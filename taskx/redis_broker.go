@@ -0,0 +1,337 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// RedisBroker 基于 go-redis 的 Broker 实现, 支持单机、Sentinel 与 Cluster
+// Cluster 模式下 Lua 脚本涉及的键需位于同一槽位, 请使用带哈希标签的命名空间, 如 WithNamespace("{taskx}")
+type RedisBroker struct {
+	client redis.UniversalClient
+}
+
+var _ Broker = (*RedisBroker)(nil)
+
+func NewRedisBroker(client redis.UniversalClient) *RedisBroker {
+	return &RedisBroker{client: client}
+}
+
+// Client 返回底层的 Redis 客户端
+func (b *RedisBroker) Client() redis.UniversalClient {
+	return b.client
+}
+
+func (b *RedisBroker) Push(ctx context.Context, queue, id string) error {
+	return b.client.LPush(ctx, queue, id).Err()
+}
+
+func (b *RedisBroker) Peek(ctx context.Context, queue string, n int) ([]string, error) {
+	return b.client.LRange(ctx, queue, 0, int64(n)-1).Result()
+}
+
+func (b *RedisBroker) Len(ctx context.Context, queue string) (int64, error) {
+	return b.client.LLen(ctx, queue).Result()
+}
+
+func (b *RedisBroker) Remove(ctx context.Context, queue, id string) error {
+	return b.client.LRem(ctx, queue, 1, id).Err()
+}
+
+// Move 使用 RPOPLPUSH 以兼容 Redis 6.2 以前的版本, 语义等同于 LMOVE RIGHT LEFT
+func (b *RedisBroker) Move(ctx context.Context, queue, processing string) (string, error) {
+	id, err := b.client.RPopLPush(ctx, queue, processing).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrQueueEmpty
+	}
+	return id, err
+}
+
+// BlockingMove 使用 BRPOPLPUSH(等价于 BLMOVE RIGHT LEFT), 阻塞期间占用一个连接
+// Redis 的阻塞超时精度为秒, 不足一秒按一秒处理; 阻塞中的命令不会因 ctx 取消而提前返回
+func (b *RedisBroker) BlockingMove(ctx context.Context, queue, processing string, timeout time.Duration) (string, error) {
+	if timeout < time.Second {
+		timeout = time.Second
+	}
+	id, err := b.client.BRPopLPush(ctx, queue, processing, timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrQueueEmpty
+	}
+	return id, err
+}
+
+func (b *RedisBroker) Ack(ctx context.Context, processing, id string) error {
+	return b.client.LRem(ctx, processing, 1, id).Err()
+}
+
+func (b *RedisBroker) RequeueAll(ctx context.Context, processing, queue string) (int, error) {
+	return requeueAllScript.Run(ctx, b.client, []string{processing, queue}).Int()
+}
+
+func (b *RedisBroker) Schedule(ctx context.Context, set, id string, at time.Time) error {
+	return b.client.ZAdd(ctx, set, &redis.Z{Score: float64(at.UnixMilli()), Member: id}).Err()
+}
+
+func (b *RedisBroker) ScheduleIfAbsent(ctx context.Context, set, id string, at time.Time) error {
+	return b.client.ZAddNX(ctx, set, &redis.Z{Score: float64(at.UnixMilli()), Member: id}).Err()
+}
+
+func (b *RedisBroker) Unschedule(ctx context.Context, set, id string) error {
+	return b.client.ZRem(ctx, set, id).Err()
+}
+
+func (b *RedisBroker) ScheduledLen(ctx context.Context, set string) (int64, error) {
+	return b.client.ZCard(ctx, set).Result()
+}
+
+func (b *RedisBroker) DueMembers(ctx context.Context, set string, before time.Time, limit int) ([]string, error) {
+	return b.client.ZRangeByScore(ctx, set, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(before.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+}
+
+func (b *RedisBroker) PromoteDue(ctx context.Context, set, queue string, now time.Time, limit int) (int, error) {
+	return promoteScript.Run(ctx, b.client, []string{set, queue}, now.UnixMilli(), limit).Int()
+}
+
+func (b *RedisBroker) EnqueueJob(ctx context.Context, args *EnqueueArgs) (bool, error) {
+	var at int64
+	if !args.At.IsZero() {
+		at = args.At.UnixMilli()
+	}
+
+	keys := []string{args.JobKey, args.Queue}
+	values := []interface{}{args.JobID, args.JobData, at}
+	if args.DedupKey != "" {
+		keys = append(keys, args.DedupKey)
+		values = append(values, args.DedupTTL.Milliseconds())
+	}
+	if args.UniqueKey != "" {
+		keys = append(keys, args.UniqueKey)
+		values = append(values, args.UniqueTTL.Milliseconds())
+	}
+
+	n, err := enqueueScript.Run(ctx, b.client, keys, values...).Int()
+	return n == 1, err
+}
+
+func (b *RedisBroker) Get(ctx context.Context, key string) (string, error) {
+	value, err := b.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
+	return value, err
+}
+
+func (b *RedisBroker) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return b.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (b *RedisBroker) Del(ctx context.Context, keys ...string) error {
+	return b.client.Del(ctx, keys...).Err()
+}
+
+func (b *RedisBroker) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return b.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (b *RedisBroker) CompareAndDelete(ctx context.Context, key, value string) (bool, error) {
+	n, err := compareAndDeleteScript.Run(ctx, b.client, []string{key}, value).Int()
+	return n == 1, err
+}
+
+func (b *RedisBroker) CompareAndExpire(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	n, err := compareAndExpireScript.Run(ctx, b.client, []string{key}, value, ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (b *RedisBroker) SetFields(ctx context.Context, key string, fields map[string]string, ttl time.Duration) error {
+	values := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		values[k] = v
+	}
+
+	pipe := b.client.TxPipeline()
+	pipe.HSet(ctx, key, values)
+	if ttl > 0 {
+		pipe.PExpire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (b *RedisBroker) GetFields(ctx context.Context, key string) (map[string]string, error) {
+	return b.client.HGetAll(ctx, key).Result()
+}
+
+// archiveDataKey 归档记录保存在与索引相邻的哈希中
+func archiveDataKey(key string) string {
+	return key + KeySeparator + "data"
+}
+
+func (b *RedisBroker) ArchiveAdd(ctx context.Context, key, id, data string, at time.Time, max int) error {
+	return archiveAddScript.Run(ctx, b.client, []string{key, archiveDataKey(key)},
+		id, data, at.UnixMilli(), max).Err()
+}
+
+func (b *RedisBroker) ArchiveList(ctx context.Context, key string, offset, limit int) ([]string, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	ids, err := b.client.ZRevRange(ctx, key, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	values, err := b.client.HMGet(ctx, archiveDataKey(key), ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]string, 0, len(values))
+	for _, v := range values {
+		if data, ok := v.(string); ok {
+			records = append(records, data)
+		}
+	}
+	return records, nil
+}
+
+func (b *RedisBroker) ArchiveGet(ctx context.Context, key, id string) (string, error) {
+	value, err := b.client.HGet(ctx, archiveDataKey(key), id).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
+	return value, err
+}
+
+func (b *RedisBroker) ArchiveRemove(ctx context.Context, key string, ids ...string) (int64, error) {
+	if len(ids) == 0 {
+		var removed *redis.IntCmd
+		_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			removed = pipe.ZCard(ctx, key)
+			pipe.Del(ctx, key, archiveDataKey(key))
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		return removed.Val(), nil
+	}
+
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+
+	var removed *redis.IntCmd
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		removed = pipe.ZRem(ctx, key, members...)
+		pipe.HDel(ctx, archiveDataKey(key), ids...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed.Val(), nil
+}
+
+func (b *RedisBroker) ArchiveLen(ctx context.Context, key string) (int64, error) {
+	return b.client.ZCard(ctx, key).Result()
+}
+
+func (b *RedisBroker) Allow(ctx context.Context, key string, limit *RateLimit, now time.Time) (time.Duration, error) {
+	var wait int64
+	var err error
+	switch limit.Algorithm {
+	case SlidingWindow:
+		wait, err = slidingWindowScript.Run(ctx, b.client, []string{key},
+			limit.Limit, limit.Window.Milliseconds(), now.UnixMilli(), uuid.New().String()).Int64()
+	default:
+		wait, err = tokenBucketScript.Run(ctx, b.client, []string{key},
+			limit.Limit, limit.Window.Milliseconds(), now.UnixMilli()).Int64()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func (b *RedisBroker) AcquireSemaphores(ctx context.Context, keys []string, limits []int, token string, ttl time.Duration) (bool, error) {
+	args := []interface{}{token, time.Now().UnixMilli(), ttl.Milliseconds()}
+	for _, limit := range limits {
+		args = append(args, limit)
+	}
+	n, err := acquireSemaphoresScript.Run(ctx, b.client, keys, args...).Int()
+	return n == 1, err
+}
+
+func (b *RedisBroker) RenewSemaphores(ctx context.Context, keys []string, token string, ttl time.Duration) (bool, error) {
+	n, err := renewSemaphoresScript.Run(ctx, b.client, keys, token, time.Now().UnixMilli(), ttl.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (b *RedisBroker) ReleaseSemaphores(ctx context.Context, keys []string, token string) error {
+	pipe := b.client.TxPipeline()
+	for _, key := range keys {
+		pipe.ZRem(ctx, key, token)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func fencingKey(key string) string {
+	return key + ":fencing"
+}
+
+func (b *RedisBroker) AcquireLease(ctx context.Context, key, holder string, ttl time.Duration) (int64, error) {
+	return acquireLeaseScript.Run(ctx, b.client, []string{key, fencingKey(key)}, holder, ttl.Milliseconds()).Int64()
+}
+
+func (b *RedisBroker) ReleaseLease(ctx context.Context, key, holder string) error {
+	return releaseLeaseScript.Run(ctx, b.client, []string{key}, holder).Err()
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, channel, message string) error {
+	return b.client.Publish(ctx, channel, message).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := b.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,26 @@
+package taskx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisBroker(t *testing.T) {
+	testBrokerContract(t, func(t *testing.T) *brokerHarness {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		return &brokerHarness{
+			broker: NewRedisBroker(client),
+			// miniredis 的过期时间不随真实时间流逝, 信号量等以客户端时间计分的数据仍需真实等待
+			elapse: func(d time.Duration) {
+				time.Sleep(d)
+				server.FastForward(d)
+			},
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff 计算第 attempt 次重试(从 1 开始)前的等待时间
+type Backoff interface {
+	Delay(attempt int) time.Duration
+}
+
+// FixedBackoff 固定间隔重试
+type FixedBackoff struct {
+	Interval time.Duration
+}
+
+func (b *FixedBackoff) Delay(attempt int) time.Duration {
+	return b.Interval
+}
+
+// ExponentialBackoff 指数退避, 第 n 次重试等待 Base*2^(n-1), 不超过 Max
+// Jitter 为 true 时在 [d/2, d) 内随机取值, 避免大量任务同时重试
+type ExponentialBackoff struct {
+	Base   time.Duration
+	Max    time.Duration
+	Jitter bool
+}
+
+func (b *ExponentialBackoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := b.Base
+	for i := 1; i < attempt && d <= math.MaxInt64/2 && (b.Max <= 0 || d < b.Max); i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+
+	if b.Jitter && d > 1 {
+		half := d / 2
+		d = half + time.Duration(rand.Int63n(int64(d-half)))
+	}
+	return d
+}
+
+// maxRetries 返回任务允许的最大重试次数
+func maxRetries(cfg *BaseTaskConfig) int {
+	switch {
+	case cfg.RetryCount < 0:
+		return 0
+	case cfg.RetryCount == 0:
+		return defaultRetryCount
+	default:
+		return cfg.RetryCount
+	}
+}
+
+// retryJob 在重试次数未耗尽时把任务放回延迟集合, 返回距下一次执行的等待时间
+// 重试进度随任务记录保存在 Redis 中, 进程重启后依然有效
+func (tm *TaskManager) retryJob(ctx context.Context, task Task, job *Job, result *TaskResult) (time.Duration, bool) {
+	cfg := baseConfigOf(task.GetConfig())
+	if job.Retried >= maxRetries(cfg) {
+		return 0, false
+	}
+
+	backoff := cfg.Backoff
+	if backoff == nil {
+		backoff = tm.backoff
+	}
+	if backoff == nil {
+		backoff = &FixedBackoff{Interval: time.Millisecond * defaultRetryDelay}
+	}
+	delay := backoff.Delay(job.Retried + 1)
+
+	retry := *job
+	retry.Retried++
+	retry.Failures = appendFailure(job.Failures, result)
+	retry.ExecuteAt = time.Now().Add(delay)
+	data, err := json.Marshal(&retry)
+	if err != nil {
+		return 0, false
+	}
+
+	_, err = tm.broker.EnqueueJob(ctx, &EnqueueArgs{
+		JobID:   job.ID,
+		JobKey:  tm.keyManager.TaskJobKey(job.ID),
+		JobData: string(data),
+		Queue:   tm.keyManager.QueueDelayedKey(tm.jobQueue(job)),
+		At:      retry.ExecuteAt,
+	})
+	if err != nil {
+		return 0, false
+	}
+
+	return delay, true
+}
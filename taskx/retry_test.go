@@ -0,0 +1,46 @@
+package taskx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedBackoff(t *testing.T) {
+	b := &FixedBackoff{Interval: time.Second}
+	for attempt := 1; attempt <= 5; attempt++ {
+		assert.Equal(t, time.Second, b.Delay(attempt))
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := &ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, b.Delay(0))
+	assert.Equal(t, 100*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, b.Delay(2))
+	assert.Equal(t, 400*time.Millisecond, b.Delay(3))
+	assert.Equal(t, 800*time.Millisecond, b.Delay(4))
+	assert.Equal(t, time.Second, b.Delay(5))
+	assert.Equal(t, time.Second, b.Delay(100))
+
+	unbounded := &ExponentialBackoff{Base: time.Second}
+	assert.Greater(t, unbounded.Delay(200), time.Duration(0))
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	b := &ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second, Jitter: true}
+
+	for i := 0; i < 100; i++ {
+		d := b.Delay(3)
+		assert.GreaterOrEqual(t, d, 200*time.Millisecond)
+		assert.Less(t, d, 400*time.Millisecond)
+	}
+}
+
+func TestMaxRetries(t *testing.T) {
+	assert.Equal(t, defaultRetryCount, maxRetries(&BaseTaskConfig{}))
+	assert.Equal(t, 5, maxRetries(&BaseTaskConfig{RetryCount: 5}))
+	assert.Equal(t, 0, maxRetries(&BaseTaskConfig{RetryCount: -1}))
+}
@@ -0,0 +1,79 @@
+package taskx
+
+import (
+	"strconv"
+	"time"
+)
+
+// scheduleEntry 记录定时任务的触发计划与下一次触发时间
+type scheduleEntry struct {
+	taskID   string
+	queue    string
+	schedule Schedule
+	next     time.Time
+}
+
+type scheduleFire struct {
+	taskID string
+	queue  string
+	at     time.Time
+}
+
+// scheduler 按各定时任务最近的触发时间唤醒, 把到期任务投递到队列
+func (tm *TaskManager) scheduler() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-tm.ctx.Done():
+			return
+		case <-timer.C:
+			timer.Reset(tm.runSchedules(time.Now()))
+		}
+	}
+}
+
+// runSchedules 触发所有到期的定时任务, 返回距下一次检查的等待时间
+// 只有 Leader 会投递, 其他实例仅推进触发时间; 进程停机期间错过的触发不会补发
+func (tm *TaskManager) runSchedules(now time.Time) time.Duration {
+	wait := time.Second * defaultScheduleInterval
+	var fires []scheduleFire
+
+	tm.mu.Lock()
+	for _, entry := range tm.schedules {
+		if entry.next.IsZero() {
+			continue
+		}
+		if !entry.next.After(now) {
+			fires = append(fires, scheduleFire{taskID: entry.taskID, queue: entry.queue, at: entry.next})
+			entry.next = entry.schedule.Next(now)
+		}
+		if d := entry.next.Sub(now); !entry.next.IsZero() && d < wait {
+			wait = d
+		}
+	}
+	tm.mu.Unlock()
+
+	if !tm.IsLeader() {
+		return wait
+	}
+	for _, fire := range fires {
+		tm.fireSchedule(fire)
+	}
+
+	return wait
+}
+
+// fireSchedule 通过 SetNX 争抢本次触发, Leader 交接期间新旧 Leader 同时触发时也只入队一次
+func (tm *TaskManager) fireSchedule(fire scheduleFire) {
+	claimKey := tm.keyManager.TaskScheduleKey(fire.taskID, fire.at.Unix())
+	claimed, err := tm.broker.SetNX(tm.ctx, claimKey, strconv.FormatInt(time.Now().Unix(), 10),
+		time.Second*defaultScheduleClaimTTL)
+	if err != nil || !claimed {
+		return
+	}
+
+	tm.markDue(tm.ctx, fire.taskID, fire.at)
+	tm.broker.Push(tm.ctx, tm.keyManager.QueueKey(fire.queue), fire.taskID)
+}
@@ -0,0 +1,79 @@
+package taskx
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newScheduleTask(runs *int32) *configTask {
+	return &configTask{
+		typ: TaskTypeSchedule,
+		cfg: &ScheduleTaskConfig{
+			BaseTaskConfig: BaseTaskConfig{ID: "tick"},
+			Cron:           "@every 1s",
+		},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(runs, 1)
+			return nil
+		},
+	}
+}
+
+func TestTaskManagerScheduleFires(t *testing.T) {
+	tm := newTestManager(t)
+
+	var runs int32
+	assert.NoError(t, tm.RegisterTask(newScheduleTask(&runs)))
+
+	tm.Start()
+	waitFor(t, 6*time.Second, func() bool {
+		return atomic.LoadInt32(&runs) >= 2
+	})
+}
+
+func TestFireScheduleClaim(t *testing.T) {
+	broker := NewMemoryBroker()
+	ctx := context.Background()
+
+	var runs int32
+	first := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(first.Stop)
+	second := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(second.Stop)
+	assert.NoError(t, first.RegisterTask(newScheduleTask(&runs)))
+	assert.NoError(t, second.RegisterTask(newScheduleTask(&runs)))
+	queueKey := first.keyManager.QueueKey(DefaultQueue)
+
+	// 非 Leader 只推进触发时间, 不投递
+	now := time.Now()
+	first.mu.Lock()
+	first.schedules["tick"].next = now.Add(-time.Second)
+	first.mu.Unlock()
+	wait := first.runSchedules(now)
+	assert.LessOrEqual(t, wait, time.Second)
+	first.mu.RLock()
+	assert.True(t, first.schedules["tick"].next.After(now))
+	first.mu.RUnlock()
+	n, err := broker.Len(ctx, queueKey)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	// Leader 交接期间新旧 Leader 触发同一时间, 只有一方入队
+	fire := scheduleFire{taskID: "tick", queue: DefaultQueue, at: now.Truncate(time.Second)}
+	first.fireSchedule(fire)
+	second.fireSchedule(fire)
+	queued, err := broker.Peek(ctx, queueKey, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tick"}, queued)
+
+	// 下一次触发时间可以正常入队
+	fire.at = fire.at.Add(time.Second)
+	second.fireSchedule(fire)
+	n, err = broker.Len(ctx, queueKey)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
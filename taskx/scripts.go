@@ -0,0 +1,180 @@
+package taskx
+
+import "github.com/go-redis/redis/v8"
+
+// promoteScript 将到期的延迟任务原子地移入派发队列
+// KEYS[1] 延迟集合, KEYS[2] 派发队列; ARGV[1] 当前时间(毫秒), ARGV[2] 单次最多移动数量
+var promoteScript = redis.NewScript(`
+local items = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, item in ipairs(items) do
+	redis.call('ZREM', KEYS[1], item)
+	redis.call('LPUSH', KEYS[2], item)
+end
+return #items
+`)
+
+// requeueAllScript 把处理列表中的全部元素放回派发队列
+// KEYS[1] 处理列表, KEYS[2] 派发队列
+var requeueAllScript = redis.NewScript(`
+local n = 0
+while redis.call('RPOPLPUSH', KEYS[1], KEYS[2]) do
+	n = n + 1
+end
+return n
+`)
+
+// enqueueScript 写入任务记录并投递到派发队列或延迟集合, 任一去重键已存在时放弃
+// KEYS[1] 任务记录, KEYS[2] 派发队列或延迟集合, KEYS[3..] 去重键(可选)
+// ARGV[1] JobID, ARGV[2] 任务记录, ARGV[3] 执行时间(毫秒, 0 表示立即), ARGV[4..] 对应去重键的有效期(毫秒)
+var enqueueScript = redis.NewScript(`
+for i = 3, #KEYS do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		return 0
+	end
+end
+for i = 3, #KEYS do
+	redis.call('SET', KEYS[i], ARGV[1], 'PX', ARGV[i + 1])
+end
+redis.call('SET', KEYS[1], ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+else
+	redis.call('LPUSH', KEYS[2], ARGV[1])
+end
+return 1
+`)
+
+// compareAndDeleteScript 仅当键的值与预期一致时删除
+// KEYS[1] 键; ARGV[1] 预期值
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// compareAndExpireScript 仅当键的值与预期一致时刷新过期时间
+// KEYS[1] 键; ARGV[1] 预期值, ARGV[2] 过期时间(毫秒)
+var compareAndExpireScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// tokenBucketScript 令牌桶限流, 放行时返回 0, 否则返回预计等待时间(毫秒)
+// KEYS[1] 限流状态(哈希); ARGV[1] 桶容量, ARGV[2] 补满时长(毫秒), ARGV[3] 当前时间(毫秒)
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = capacity / window
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return wait
+`)
+
+// slidingWindowScript 滑动窗口限流, 放行时返回 0, 否则返回最早一次放行移出窗口的等待时间(毫秒)
+// KEYS[1] 放行记录(有序集合); ARGV[1] 窗口内最大次数, ARGV[2] 窗口(毫秒), ARGV[3] 当前时间(毫秒), ARGV[4] 记录成员
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return math.max(1, tonumber(oldest[2]) + window - now)
+`)
+
+// acquireSemaphoresScript 同时占用多个信号量的名额, 任一已满时全部放弃
+// 信号量为有序集合, 成员为占用令牌, score 为名额过期时间(毫秒)
+// KEYS 信号量; ARGV[1] 令牌, ARGV[2] 当前时间(毫秒), ARGV[3] 有效期(毫秒), ARGV[3+i] 第 i 个信号量的容量
+var acquireSemaphoresScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+for i, key in ipairs(KEYS) do
+	redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+	if not redis.call('ZSCORE', key, ARGV[1]) and redis.call('ZCARD', key) >= tonumber(ARGV[3 + i]) then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call('ZADD', key, now + tonumber(ARGV[3]), ARGV[1])
+	redis.call('PEXPIRE', key, ARGV[3])
+end
+return 1
+`)
+
+// renewSemaphoresScript 延长令牌占用的名额, 任一名额已失效时返回 0
+// KEYS 信号量; ARGV[1] 令牌, ARGV[2] 当前时间(毫秒), ARGV[3] 有效期(毫秒)
+var renewSemaphoresScript = redis.NewScript(`
+local now = tonumber(ARGV[2])
+local held = 1
+for _, key in ipairs(KEYS) do
+	local score = redis.call('ZSCORE', key, ARGV[1])
+	if score and tonumber(score) > now then
+		redis.call('ZADD', key, now + tonumber(ARGV[3]), ARGV[1])
+		redis.call('PEXPIRE', key, ARGV[3])
+	else
+		held = 0
+	end
+end
+return held
+`)
+
+// acquireLeaseScript 争抢或续期租约, 返回 fencing token, 租约被他人持有时返回 0
+// KEYS[1] 租约(哈希), KEYS[2] fencing token 计数器; ARGV[1] 持有者, ARGV[2] 有效期(毫秒)
+var acquireLeaseScript = redis.NewScript(`
+local holder = redis.call('HGET', KEYS[1], 'holder')
+if holder == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return tonumber(redis.call('HGET', KEYS[1], 'token'))
+end
+if holder then
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], 'holder', ARGV[1], 'token', token)
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return token
+`)
+
+// releaseLeaseScript 仅当租约属于持有者时删除
+// KEYS[1] 租约; ARGV[1] 持有者
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'holder') == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// archiveAddScript 写入归档记录并淘汰超出容量的最早记录
+// KEYS[1] 归档索引(有序集合), KEYS[2] 归档数据(哈希)
+// ARGV[1] 记录ID, ARGV[2] 记录数据, ARGV[3] 时间(毫秒), ARGV[4] 最大保留数量
+var archiveAddScript = redis.NewScript(`
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+local overflow = redis.call('ZCARD', KEYS[1]) - tonumber(ARGV[4])
+if overflow > 0 then
+	local expired = redis.call('ZRANGE', KEYS[1], 0, overflow - 1)
+	redis.call('ZREMRANGEBYRANK', KEYS[1], 0, overflow - 1)
+	redis.call('HDEL', KEYS[2], unpack(expired))
+end
+return 1
+`)
@@ -0,0 +1,130 @@
+package taskx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ShutdownError 表示 Shutdown 等待超时, Running 为超时时仍在执行的任务
+// 这些任务会被取消并放回队列, 未能放回的由其他实例的 reaper 回收
+type ShutdownError struct {
+	Running []*Job
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("%s: %d job(s) still running", ErrShutdownTimeout, len(e.Running))
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return ErrShutdownTimeout
+}
+
+// Shutdown 优雅关闭任务管理器
+// 立即停止调度与派发, 已派发但未开始的任务放回队列, 然后等待正在执行的任务结束
+// ctx 结束时仍未完成的任务会被取消并放回队列, 返回 *ShutdownError; 返回前最多再等待 defaultShutdownGrace 让它们放回
+// 重复调用返回 ErrManagerStopped
+func (tm *TaskManager) Shutdown(ctx context.Context) error {
+	first := false
+	tm.stopOnce.Do(func() {
+		first = true
+	})
+	if !first {
+		return ErrManagerStopped
+	}
+
+	// 停止后台循环, 之后不会再有新任务派发给 Worker
+	tm.mu.Lock()
+	started := tm.started
+	tm.cancel()
+	tm.mu.Unlock()
+	tm.loops.Wait()
+
+	if !started {
+		tm.execCancel()
+		return nil
+	}
+
+	// 包括缩容后仍在收尾的 Worker
+	workers := tm.allWorkers()
+	for _, worker := range workers {
+		worker.Stop()
+	}
+	for _, worker := range workers {
+		<-worker.doneCh
+		worker.requeueBuffered()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, worker := range workers {
+			worker.inflight.Wait()
+		}
+		close(done)
+	}()
+
+	// 优先检查 done, 已结束的 ctx(如 Stop)不会把空闲的关闭误报为超时
+	var running []*Job
+	select {
+	case <-done:
+	default:
+		select {
+		case <-done:
+		case <-ctx.Done():
+			for _, worker := range workers {
+				running = append(running, worker.runningJobs()...)
+			}
+		}
+	}
+	tm.execCancel()
+
+	// 超时后被取消的任务仍需放回队列, 再最多等待 defaultShutdownGrace 后注销 Worker
+	wait := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		wait, cancel = context.WithTimeout(context.Background(), time.Second*defaultShutdownGrace)
+		defer cancel()
+	}
+	select {
+	case <-done:
+		// 阻塞派发的命令可能仍在等待, 未及时返回时保留注册信息, 之后领取到的任务由 reaper 回收
+		if tm.waitDrained(wait) {
+			for _, worker := range workers {
+				worker.deregister()
+			}
+		}
+	case <-wait.Done():
+	}
+
+	if len(running) > 0 {
+		return &ShutdownError{Running: running}
+	}
+	return nil
+}
+
+// waitDrained 等待阻塞派发遗留的命令返回, ctx 结束前未返回时返回 false
+func (tm *TaskManager) waitDrained(ctx context.Context) bool {
+	select {
+	case <-tm.drained:
+		return true
+	default:
+	}
+	select {
+	case <-tm.drained:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// requeue 把 Worker 处理列表中的任务放回队列
+func (tm *TaskManager) requeue(ctx context.Context, workerID string, job *Job) {
+	queue := tm.jobQueue(job)
+	err := handOff(ctx, tm.broker, tm.keyManager.QueueProcessingKey(workerID, queue), job.ID, func() error {
+		return tm.broker.Push(ctx, tm.keyManager.QueueKey(queue), job.ID)
+	})
+	if err != nil {
+		return
+	}
+	tm.setStatus(ctx, job, TaskStatusPending, "", nil)
+}
@@ -0,0 +1,140 @@
+package taskx
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskManagerShutdownDrain(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var done int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "slow"},
+		execute: func(ctx context.Context) error {
+			time.Sleep(200 * time.Millisecond)
+			atomic.AddInt32(&done, 1)
+			return nil
+		},
+	}))
+
+	job, err := tm.Enqueue(ctx, "slow")
+	assert.NoError(t, err)
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, job.ID)
+		return err == nil && status.Status == TaskStatusRunning
+	})
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	assert.NoError(t, tm.Shutdown(shutdownCtx))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&done))
+
+	status, err := tm.GetStatus(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, TaskStatusCompleted, status.Status)
+
+	assert.ErrorIs(t, tm.Shutdown(shutdownCtx), ErrManagerStopped)
+	_, err = tm.Enqueue(ctx, "slow")
+	assert.ErrorIs(t, err, ErrManagerStopped)
+}
+
+func TestTaskManagerShutdownTimeout(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var started int32
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "block"},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+
+	// 池大小为 2, 两个任务执行, 第三个停留在 Worker 缓冲中
+	for i := 0; i < 3; i++ {
+		_, err := tm.Enqueue(ctx, "block")
+		assert.NoError(t, err)
+	}
+
+	tm.Start()
+	queueKey := tm.keyManager.TaskQueueKey()
+	waitFor(t, 5*time.Second, func() bool {
+		entries, _ := tm.broker.Peek(ctx, queueKey, 10)
+		return atomic.LoadInt32(&started) == 2 && len(entries) == 0
+	})
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err := tm.Shutdown(shutdownCtx)
+
+	var shutdownErr *ShutdownError
+	if assert.ErrorAs(t, err, &shutdownErr) {
+		assert.Len(t, shutdownErr.Running, 2)
+	}
+	assert.ErrorIs(t, err, ErrShutdownTimeout)
+
+	// 返回时被取消的任务与缓冲中的任务都已放回队列, 不进入死信, Worker 已注销
+	entries, _ := tm.broker.Peek(ctx, queueKey, 10)
+	assert.Len(t, entries, 3)
+	count, err := tm.CountDeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	workers, _ := tm.broker.ScheduledLen(ctx, tm.keyManager.WorkerRegistryKey())
+	assert.Zero(t, workers)
+}
+
+func TestTaskManagerShutdownContinuous(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	var started int32
+	assert.NoError(t, tm.RegisterTask(&configTask{
+		typ: TaskTypeContinuous,
+		cfg: &ContinuousTaskConfig{
+			BaseTaskConfig: BaseTaskConfig{ID: "poll"},
+			Interval:       time.Second,
+		},
+		execute: func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}))
+
+	tm.Start()
+	waitFor(t, 5*time.Second, func() bool {
+		return atomic.LoadInt32(&started) == 1
+	})
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tm.Shutdown(shutdownCtx), ErrShutdownTimeout)
+
+	// 被取消的持续任务只放回队列一次, 不再按间隔重复安排
+	entries, _ := tm.broker.Peek(ctx, tm.keyManager.QueueKey(DefaultQueue), 10)
+	assert.Equal(t, []string{"poll"}, entries)
+	n, err := tm.broker.ScheduledLen(ctx, tm.keyManager.QueueDelayedKey(DefaultQueue))
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestTaskManagerStopIdle(t *testing.T) {
+	// 已结束的 ctx 不会让空闲的关闭返回超时
+	for i := 0; i < 20; i++ {
+		tm := NewTaskManagerWithBroker(NewMemoryBroker(), WithWorkerSize(1))
+		tm.Start()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.NoError(t, tm.Shutdown(ctx))
+	}
+}
@@ -0,0 +1,182 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var taskStatusNames = map[TaskStatus]string{
+	TaskStatusPending:   "pending",
+	TaskStatusRunning:   "running",
+	TaskStatusCompleted: "completed",
+	TaskStatusFailed:    "failed",
+	TaskStatusTimeout:   "timeout",
+	TaskStatusCancelled: "cancelled",
+}
+
+func (s TaskStatus) String() string {
+	if name, ok := taskStatusNames[s]; ok {
+		return name
+	}
+	return "unknown(" + strconv.Itoa(int(s)) + ")"
+}
+
+func (s TaskStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *TaskStatus) UnmarshalText(text []byte) error {
+	for status, name := range taskStatusNames {
+		if name == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown task status %q", text)
+}
+
+// JobStatus 保存在 TaskStatusKey 下的任务状态, 可跨实例查询
+type JobStatus struct {
+	JobID     string         `json:"job_id"`
+	TaskID    string         `json:"task_id"`
+	Status    TaskStatus     `json:"status"`
+	Attempt   int            `json:"attempt"`
+	WorkerID  string         `json:"worker_id,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Result    *AttemptRecord `json:"result,omitempty"`
+	// Progress 最近一次上报的进度, 每次开始执行时清空
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+const (
+	statusFieldJobID     = "job_id"
+	statusFieldTaskID    = "task_id"
+	statusFieldStatus    = "status"
+	statusFieldAttempt   = "attempt"
+	statusFieldWorkerID  = "worker_id"
+	statusFieldUpdatedAt = "updated_at"
+	statusFieldResult    = "result"
+	statusFieldProgress  = "progress"
+)
+
+// setStatus 写入状态变更并刷新过期时间, result 为空时保留上一次的执行结果
+func (tm *TaskManager) setStatus(ctx context.Context, job *Job, status TaskStatus, workerID string, result *TaskResult) error {
+	values := map[string]string{
+		statusFieldJobID:     job.ID,
+		statusFieldTaskID:    job.TaskID,
+		statusFieldStatus:    status.String(),
+		statusFieldAttempt:   strconv.Itoa(job.Retried + 1),
+		statusFieldWorkerID:  workerID,
+		statusFieldUpdatedAt: time.Now().Format(time.RFC3339Nano),
+	}
+	if status == TaskStatusRunning {
+		values[statusFieldProgress] = ""
+	}
+	if result != nil {
+		data, err := json.Marshal(newAttemptRecord(result))
+		if err != nil {
+			return err
+		}
+		values[statusFieldResult] = string(data)
+	}
+
+	return tm.broker.SetFields(ctx, tm.keyManager.TaskStatusKey(job.ID), values, tm.statusTTL)
+}
+
+// recordResult 把任务的最终结果写入最近执行结果归档, 超出容量时淘汰最早的记录
+func (tm *TaskManager) recordResult(ctx context.Context, job *Job, workerID string, result *TaskResult) error {
+	record := newAttemptRecord(result)
+	record.StackTrace = ""
+	status := &JobStatus{
+		JobID:     job.ID,
+		TaskID:    job.TaskID,
+		Status:    result.Status,
+		Attempt:   result.Attempt,
+		WorkerID:  workerID,
+		UpdatedAt: result.EndTime,
+		Result:    &record,
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	// 系统调度的任务每次执行使用相同的 JobID, 归档使用独立的记录ID
+	return tm.broker.ArchiveAdd(ctx, tm.keyManager.TaskResultsKey(),
+		uuid.New().String(), string(data), status.UpdatedAt, defaultRecentResultMax)
+}
+
+// ListRecentResults 按结束时间倒序分页列出最近结束的任务, 仅保留最近的 1000 条
+func (tm *TaskManager) ListRecentResults(ctx context.Context, offset, limit int) ([]*JobStatus, error) {
+	records, err := tm.broker.ArchiveList(ctx, tm.keyManager.TaskResultsKey(), offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*JobStatus, 0, len(records))
+	for _, data := range records {
+		status := &JobStatus{}
+		if err := json.Unmarshal([]byte(data), status); err != nil {
+			continue
+		}
+		results = append(results, status)
+	}
+	return results, nil
+}
+
+// GetStatus 查询任务状态, id 为 Enqueue 返回的 JobID; 系统调度的任务使用任务ID查询最近一次执行
+func (tm *TaskManager) GetStatus(ctx context.Context, id string) (*JobStatus, error) {
+	values, err := tm.broker.GetFields(ctx, tm.keyManager.TaskStatusKey(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return parseJobStatus(values)
+}
+
+func parseJobStatus(values map[string]string) (*JobStatus, error) {
+	status := &JobStatus{
+		JobID:    values[statusFieldJobID],
+		TaskID:   values[statusFieldTaskID],
+		WorkerID: values[statusFieldWorkerID],
+	}
+
+	if err := status.Status.UnmarshalText([]byte(values[statusFieldStatus])); err != nil {
+		return nil, err
+	}
+	if v := values[statusFieldAttempt]; v != "" {
+		attempt, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		status.Attempt = attempt
+	}
+	if v := values[statusFieldUpdatedAt]; v != "" {
+		updatedAt, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, err
+		}
+		status.UpdatedAt = updatedAt
+	}
+	if v := values[statusFieldResult]; v != "" {
+		status.Result = &AttemptRecord{}
+		if err := json.Unmarshal([]byte(v), status.Result); err != nil {
+			return nil, err
+		}
+	}
+	if v := values[statusFieldProgress]; v != "" {
+		status.Progress = &Progress{}
+		if err := json.Unmarshal([]byte(v), status.Progress); err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
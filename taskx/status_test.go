@@ -0,0 +1,51 @@
+package taskx
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskStatusText(t *testing.T) {
+	for status, name := range taskStatusNames {
+		assert.Equal(t, name, status.String())
+
+		var parsed TaskStatus
+		assert.NoError(t, parsed.UnmarshalText([]byte(name)))
+		assert.Equal(t, status, parsed)
+	}
+
+	var parsed TaskStatus
+	assert.Error(t, parsed.UnmarshalText([]byte("sleeping")))
+	assert.Equal(t, "unknown(42)", TaskStatus(42).String())
+}
+
+func TestParseJobStatus(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	record := AttemptRecord{Attempt: 2, Status: TaskStatusFailed, Error: "boom"}
+	data, err := json.Marshal(record)
+	assert.NoError(t, err)
+
+	status, err := parseJobStatus(map[string]string{
+		statusFieldJobID:     "job-1",
+		statusFieldTaskID:    "task-1",
+		statusFieldStatus:    "failed",
+		statusFieldAttempt:   "2",
+		statusFieldWorkerID:  "worker-0",
+		statusFieldUpdatedAt: now.Format(time.RFC3339Nano),
+		statusFieldResult:    string(data),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "job-1", status.JobID)
+	assert.Equal(t, "task-1", status.TaskID)
+	assert.Equal(t, TaskStatusFailed, status.Status)
+	assert.Equal(t, 2, status.Attempt)
+	assert.Equal(t, "worker-0", status.WorkerID)
+	assert.True(t, now.Equal(status.UpdatedAt))
+	assert.Equal(t, &record, status.Result)
+
+	_, err = parseJobStatus(map[string]string{statusFieldStatus: "bogus"})
+	assert.Error(t, err)
+}
@@ -2,9 +2,27 @@ package taskx
 
 import (
 	"context"
+	"strconv"
 	"time"
 )
 
+var taskTypeNames = map[TaskType]string{
+	TaskTypeSchedule:   "schedule",
+	TaskTypeContinuous: "continuous",
+	TaskTypeOnce:       "once",
+}
+
+func (t TaskType) String() string {
+	if name, ok := taskTypeNames[t]; ok {
+		return name
+	}
+	return "unknown(" + strconv.Itoa(int(t)) + ")"
+}
+
+func (t TaskType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
 type Task interface {
 	Execute(ctx context.Context) error
 	GetID() string
@@ -20,27 +38,80 @@ type BaseTaskConfig struct {
 	ID          string
 	Description string
 	Timeout     time.Duration
-	RetryCount  int
-	Tags        []string
+	// RetryCount 失败后的最大重试次数, 0 使用默认值, 负数表示不重试
+	RetryCount int
+	// Backoff 重试间隔策略, 为空时使用 TaskManager 的默认策略
+	Backoff Backoff
+	// Queue 任务默认投递的队列, 为空时使用默认队列
+	Queue string
+	// RateLimit 集群内共享的执行频率限制, 超出时任务被延后派发
+	RateLimit *RateLimit
+	// MaxConcurrency 集群内同时执行的最大数量, 0 表示不限制
+	MaxConcurrency int
+	// Tags 任务标签, 可通过 WithTagConcurrency 限制同一标签的并发数量
+	Tags []string
 }
 
 func (c *BaseTaskConfig) Validate() error {
 	if c.ID == "" {
 		return ErrInvalidConfig
 	}
+	if c.RateLimit != nil && !c.RateLimit.valid() {
+		return ErrInvalidConfig
+	}
+	if c.MaxConcurrency < 0 {
+		return ErrInvalidConfig
+	}
 	return nil
 }
 
+// Base 返回基础配置, 嵌入 BaseTaskConfig 的配置会自动获得该方法
+func (c *BaseTaskConfig) Base() *BaseTaskConfig {
+	return c
+}
+
+type baseConfigProvider interface {
+	Base() *BaseTaskConfig
+}
+
+// baseConfigOf 从任意任务配置中取出基础配置, 不存在时返回空配置
+func baseConfigOf(cfg TaskConfig) *BaseTaskConfig {
+	if p, ok := cfg.(baseConfigProvider); ok {
+		return p.Base()
+	}
+	return &BaseTaskConfig{}
+}
+
 type ScheduleTaskConfig struct {
 	BaseTaskConfig
 	Cron string
 }
 
+func (c *ScheduleTaskConfig) Validate() error {
+	if err := c.BaseTaskConfig.Validate(); err != nil {
+		return err
+	}
+	_, err := ParseCron(c.Cron)
+	return err
+}
+
 type ContinuousTaskConfig struct {
 	BaseTaskConfig
+	// Interval 两次执行之间的间隔, 到期任务每秒提升一次, 小于 1 秒时按约 1 秒执行
 	Interval time.Duration
 }
 
+func (c *ContinuousTaskConfig) Validate() error {
+	if err := c.BaseTaskConfig.Validate(); err != nil {
+		return err
+	}
+	if c.Interval <= 0 {
+		return ErrInvalidConfig
+	}
+	return nil
+}
+
+// OnceTaskConfig 单次任务配置, ExecuteAt 为零值时立即执行
 type OnceTaskConfig struct {
 	BaseTaskConfig
 	ExecuteAt time.Time
@@ -0,0 +1,35 @@
+package taskx
+
+import (
+	"context"
+)
+
+// Tracer 链路追踪扩展点, 可以对接 OpenTelemetry 等实现, 未配置时不产生任何开销
+// 投递时把链路上下文写入任务记录, 执行时在执行实例上恢复, 从而把投递与执行串成一条链路
+type Tracer interface {
+	// Inject 在投递时调用, 把 ctx 中的链路上下文写入 carrier, carrier 随任务记录保存
+	Inject(ctx context.Context, carrier map[string]string)
+	// Start 在执行前调用, 从 carrier 恢复链路上下文并开启 span
+	// 返回的 ctx 传给 Execute, 返回的函数在执行结束后以执行结果调用
+	Start(ctx context.Context, task Task, job *Job, carrier map[string]string) (context.Context, func(result *TaskResult))
+}
+
+// injectTrace 把投递方的链路上下文写入任务记录
+func (tm *TaskManager) injectTrace(ctx context.Context, job *Job) {
+	if tm.tracer == nil {
+		return
+	}
+	carrier := make(map[string]string)
+	tm.tracer.Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		job.Trace = carrier
+	}
+}
+
+// startTrace 为一次执行开启 span, 未配置 Tracer 时原样返回
+func (tm *TaskManager) startTrace(ctx context.Context, task Task, job *Job) (context.Context, func(result *TaskResult)) {
+	if tm.tracer == nil {
+		return ctx, func(*TaskResult) {}
+	}
+	return tm.tracer.Start(ctx, task, job, job.Trace)
+}
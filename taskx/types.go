@@ -18,11 +18,14 @@ const (
 	TaskStatusCompleted
 	TaskStatusFailed
 	TaskStatusTimeout
+	TaskStatusCancelled
 )
 
 // TaskResult 任务执行结果
 type TaskResult struct {
 	TaskID     string
+	JobID      string
+	Attempt    int // 第几次执行, 从 1 开始
 	Status     TaskStatus
 	StartTime  time.Time
 	EndTime    time.Time
@@ -30,4 +33,6 @@ type TaskResult struct {
 	Error      error
 	PanicError interface{}
 	StackTrace []byte
+	// Output 任务通过 SetResult 设置的结果(已编码)
+	Output []byte
 }
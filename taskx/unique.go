@@ -0,0 +1,87 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// UniqueMode 唯一键冲突时的处理方式
+type UniqueMode int
+
+const (
+	// UniqueReject 返回 *DuplicateJobError
+	UniqueReject UniqueMode = iota
+	// UniqueMerge 不入队新任务, 返回已存在的任务, 其 Duplicate 为 true
+	UniqueMerge
+)
+
+// DuplicateJobError 表示去重键或唯一键已被其他任务占用, 可通过 errors.Is(err, ErrDuplicateJob) 判断
+type DuplicateJobError struct {
+	// Key 冲突的去重键或唯一键
+	Key string
+	// JobID 占用该键的任务, 键恰好过期时为空
+	JobID string
+}
+
+func (e *DuplicateJobError) Error() string {
+	return ErrDuplicateJob.Error() + ": key " + e.Key + " held by job " + e.JobID
+}
+
+func (e *DuplicateJobError) Unwrap() error {
+	return ErrDuplicateJob
+}
+
+// WithUnique 声明唯一键, 在 ttl 时间窗口内同一唯一键只会入队一次, 无论任务是否已经执行完毕
+func WithUnique(key string, ttl time.Duration) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.UniqueKey = key
+		o.UniqueTTL = ttl
+	}
+}
+
+func WithUniqueMode(mode UniqueMode) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.UniqueMode = mode
+	}
+}
+
+// resolveDuplicate 处理入队时的键冲突, 按 UniqueMode 返回错误或已存在的任务
+func (tm *TaskManager) resolveDuplicate(ctx context.Context, job *Job, options *EnqueueOptions) (*Job, error) {
+	var key, existing string
+	if job.UniqueKey != "" {
+		if id, err := tm.broker.Get(ctx, tm.keyManager.TaskUniqueKey(job.UniqueKey)); err == nil {
+			key, existing = job.UniqueKey, id
+		}
+	}
+	if existing == "" && job.DedupKey != "" {
+		if id, err := tm.broker.Get(ctx, tm.keyManager.TaskDedupKey(job.DedupKey)); err == nil {
+			key, existing = job.DedupKey, id
+		}
+	}
+	if key == "" {
+		key = job.UniqueKey
+		if key == "" {
+			key = job.DedupKey
+		}
+	}
+
+	if options.UniqueMode != UniqueMerge || existing == "" {
+		return nil, &DuplicateJobError{Key: key, JobID: existing}
+	}
+
+	// 已存在的任务可能已经执行完毕, 此时任务记录已被清理
+	merged := &Job{ID: existing, TaskID: job.TaskID}
+	data, err := tm.broker.Get(ctx, tm.keyManager.TaskJobKey(existing))
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(data), merged); err != nil {
+			return nil, err
+		}
+	}
+	merged.Duplicate = true
+	return merged, nil
+}
@@ -0,0 +1,48 @@
+package taskx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskManagerUniqueJobs(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "checkout"},
+		execute:        func(ctx context.Context) error { return nil },
+	}))
+
+	first, err := tm.Enqueue(ctx, "checkout", WithUnique("order-1", 100*time.Millisecond), WithPayload(1))
+	assert.NoError(t, err)
+	assert.False(t, first.Duplicate)
+
+	// 默认拒绝, 错误中带有已存在的任务
+	_, err = tm.Enqueue(ctx, "checkout", WithUnique("order-1", 100*time.Millisecond))
+	assert.ErrorIs(t, err, ErrDuplicateJob)
+	var dup *DuplicateJobError
+	if assert.ErrorAs(t, err, &dup) {
+		assert.Equal(t, "order-1", dup.Key)
+		assert.Equal(t, first.ID, dup.JobID)
+	}
+
+	// 合并时返回已存在的任务
+	merged, err := tm.Enqueue(ctx, "checkout", WithUnique("order-1", 100*time.Millisecond), WithUniqueMode(UniqueMerge))
+	assert.NoError(t, err)
+	assert.True(t, merged.Duplicate)
+	assert.Equal(t, first.ID, merged.ID)
+	assert.Equal(t, first.Payload, merged.Payload)
+
+	_, err = tm.Enqueue(ctx, "checkout", WithUnique("order-1", 0))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	// 时间窗口结束后可以再次入队
+	time.Sleep(150 * time.Millisecond)
+	second, err := tm.Enqueue(ctx, "checkout", WithUnique("order-1", time.Minute))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+}
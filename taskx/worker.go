@@ -2,62 +2,381 @@ package taskx
 
 import (
 	"context"
+	"errors"
 	"runtime/debug"
+	"strconv"
+	"sync"
 	"time"
 )
 
 type Worker struct {
 	id       string
-	poolSize int
-	tasks    chan Task
+	pool     *workerPool
+	tasks    chan delivery
 	tm       *TaskManager
 	stopCh   chan struct{}
+	stopOnce sync.Once
+	doneCh   chan struct{}
+	// heartbeatStop 在缩容后停止心跳, heartbeatDone 在心跳协程退出时关闭
+	heartbeatStop chan struct{}
+	heartbeatDone chan struct{}
+	inflight      sync.WaitGroup
+	mu            sync.Mutex
+	running       map[string]*Job
+	// selector 决定领取任务时尝试各队列的顺序
+	selector *queueSelector
+	// shared 为 true 表示处理所有队列, 只有共享 Worker 参与自动伸缩
+	shared bool
+	// reserved 阻塞派发为该 Worker 预留的名额, 仅由调度协程读写
+	reserved int
+	// offerMu 保证停止后不会再有任务进入缓冲
+	offerMu sync.Mutex
 }
 
 func NewWorker(id string, poolSize int, tm *TaskManager) *Worker {
+	return newWorker(id, poolSize, poolSize, tm)
+}
+
+// newWorker 创建协程池大小可以在 maxPoolSize 以内调整的 Worker
+func newWorker(id string, poolSize, maxPoolSize int, tm *TaskManager) *Worker {
+	if maxPoolSize < poolSize {
+		maxPoolSize = poolSize
+	}
 	return &Worker{
 		id:       id,
-		poolSize: poolSize,
-		tasks:    make(chan Task, poolSize),
+		pool:     newWorkerPool(poolSize),
+		tasks:    make(chan delivery, maxPoolSize),
 		tm:       tm,
 		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+		running:  make(map[string]*Job),
+		selector: newQueueSelector([]Queue{{Name: DefaultQueue}}, true),
+
+		heartbeatStop: make(chan struct{}),
+		heartbeatDone: make(chan struct{}),
+	}
+}
+
+// workerPool 可调整大小的协程池, 缩小时已占用的名额在任务结束后才会收回
+type workerPool struct {
+	mu   sync.Mutex
+	size int
+	used int
+	// released 在名额释放或扩容时关闭并替换, 用于唤醒等待者
+	released chan struct{}
+}
+
+func newWorkerPool(size int) *workerPool {
+	return &workerPool{size: size, released: make(chan struct{})}
+}
+
+// tryAcquire 占用一个名额, 没有空闲名额时返回可等待的通知
+func (p *workerPool) tryAcquire() (bool, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.used < p.size {
+		p.used++
+		return true, nil
+	}
+	return false, p.released
+}
+
+func (p *workerPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.used--
+	p.notifyLocked()
+}
+
+func (p *workerPool) resize(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.size = size
+	p.notifyLocked()
+}
+
+func (p *workerPool) capacity() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+func (p *workerPool) notifyLocked() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// PoolSize 返回当前的协程池大小
+func (w *Worker) PoolSize() int {
+	return w.pool.capacity()
+}
+
+// freeSlots 返回调度协程还可以派发给该 Worker 的任务数
+func (w *Worker) freeSlots() int {
+	return w.PoolSize() - len(w.tasks) - w.reserved
+}
+
+// setPoolSize 调整协程池大小, 不超过创建时的上限
+func (w *Worker) setPoolSize(size int) {
+	if size > cap(w.tasks) {
+		size = cap(w.tasks)
+	}
+	if size < 1 {
+		size = 1
 	}
+	w.pool.resize(size)
 }
 
+// Start 持续领取并执行任务, 直到 Stop 被调用或 ctx 结束
+// ctx 同时作为任务的执行上下文, 取消后正在运行的任务也会收到取消信号
 func (w *Worker) Start(ctx context.Context) {
-	pool := make(chan struct{}, w.poolSize)
+	defer close(w.doneCh)
 
-	go w.heartbeat(ctx)
+	go func() {
+		defer close(w.heartbeatDone)
+		w.heartbeat(ctx)
+	}()
 
 	for {
+		// 先占用协程池再领取任务, 工作池满时任务留在缓冲中等待
+		acquired, released := w.pool.tryAcquire()
+		if !acquired {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stopCh:
+				return
+			case <-released:
+				continue
+			}
+		}
+
 		select {
 		case <-ctx.Done():
+			w.pool.release()
 			return
 		case <-w.stopCh:
+			w.pool.release()
 			return
-		case task := <-w.tasks:
-			select {
-			case pool <- struct{}{}:
-				go func(t Task) {
-					defer func() {
-						<-pool
-					}()
-					w.executeTask(ctx, t)
-				}(task)
-			default:
-				// 工作池满，等待下一次调度
-			}
+		case d := <-w.tasks:
+			w.track(d.job)
+			go func(d delivery) {
+				defer func() {
+					w.untrack(d.job)
+					w.pool.release()
+					w.tm.notifyFreed()
+				}()
+				w.executeTask(ctx, d)
+			}(d)
 		}
 	}
 }
 
+// Stop 停止领取新任务, 已开始的任务不受影响, 可以重复调用
 func (w *Worker) Stop() {
-	close(w.stopCh)
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+}
+
+func (w *Worker) track(job *Job) {
+	w.inflight.Add(1)
+	w.mu.Lock()
+	w.running[job.ID] = job
+	w.mu.Unlock()
+}
+
+func (w *Worker) untrack(job *Job) {
+	w.mu.Lock()
+	delete(w.running, job.ID)
+	w.mu.Unlock()
+	w.inflight.Done()
+}
+
+// runningJobs 返回正在执行的任务
+func (w *Worker) runningJobs() []*Job {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	jobs := make([]*Job, 0, len(w.running))
+	for _, job := range w.running {
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// offer 把任务放入 Worker 的缓冲, Worker 已停止时返回 false
+func (w *Worker) offer(d delivery) bool {
+	w.offerMu.Lock()
+	defer w.offerMu.Unlock()
+
+	select {
+	case <-w.stopCh:
+		return false
+	default:
+	}
+	w.tasks <- d
+	return true
 }
 
-func (w *Worker) executeTask(ctx context.Context, task Task) {
-	result := &TaskResult{
+// requeueBuffered 把已派发但尚未开始执行的任务放回队列, 需在 Start 返回后调用
+func (w *Worker) requeueBuffered() {
+	// 等待进行中的 offer 完成, 之后不会再有任务进入缓冲
+	w.offerMu.Lock()
+	defer w.offerMu.Unlock()
+
+	for {
+		select {
+		case d := <-w.tasks:
+			w.tm.requeue(context.Background(), w.id, d.job)
+		default:
+			return
+		}
+	}
+}
+
+// stopHeartbeat 停止心跳并等待心跳协程退出, 需在 Start 之后调用
+func (w *Worker) stopHeartbeat() {
+	close(w.heartbeatStop)
+	<-w.heartbeatDone
+}
+
+// deregister 正常退出后清理心跳与注册信息
+func (w *Worker) deregister() {
+	ctx := context.Background()
+	w.tm.broker.Del(ctx, w.tm.keyManager.WorkerHeartbeatKey(w.id))
+	w.tm.broker.Unschedule(ctx, w.tm.keyManager.WorkerRegistryKey(), w.id)
+}
+
+// executeTask 执行任务并记录结果, ctx 仅用于任务执行
+// 状态、确认等记录操作使用独立的上下文, 保证关闭期间被取消的任务也能完成收尾
+func (w *Worker) executeTask(ctx context.Context, d delivery) {
+	task, job := d.task, d.job
+	bg := context.Background()
+
+	// 执行结束或主动跳过后从处理列表确认移除
+	// 放回队列的路径由 deferJob、requeue 自行确认, 放回失败时保留在处理列表中由 reaper 回收
+	ack := true
+	defer func() {
+		if ack {
+			w.tm.broker.Ack(bg, w.tm.keyManager.QueueProcessingKey(w.id, w.tm.jobQueue(job)), job.ID)
+		}
+	}()
+	// 持续任务结束后按间隔重新安排, 关闭时放回队列的任务已有排队副本, 不再重复安排
+	rearm := true
+	defer func() {
+		if rearm {
+			w.tm.rearmContinuous(bg, task)
+		}
+	}()
+
+	// 获取任务锁, 系统调度的任务以任务ID作为 JobID, 因此同一任务不会并发执行
+	lockKey := w.tm.keyManager.TaskLockKey(job.ID)
+	lock, err := w.tm.acquireLock(ctx, lockKey)
+	if err != nil {
+		// 锁被占用说明同一任务正在执行, 跳过本次; Broker 异常时延后重新派发
+		if !errors.Is(err, ErrTaskLockFailed) {
+			ack = false
+			w.tm.deferJob(bg, w.id, job.ID, job, time.Second*defaultLockRetryDelay)
+		}
+		return
+	}
+	defer lock.release(bg)
+
+	// 已被取消的任务不再执行
+	if w.tm.isCancelled(bg, job.ID) {
+		w.tm.finishCancelled(bg, job, w.id, nil)
+		return
+	}
+
+	// 占用并发名额, 名额已满或 Broker 异常时延后派发
+	slots, err := w.tm.acquireSlots(ctx, task)
+	if err != nil {
+		ack = false
+		w.tm.deferJob(bg, w.id, job.ID, job, time.Second*defaultConcurrencyDelay)
+		return
+	}
+	if slots != nil {
+		defer slots.release(bg)
+	}
+
+	// 执行任务
+	w.tm.metrics.observeLatency(w.tm.jobQueue(job), job, time.Now())
+	w.tm.setStatus(bg, job, TaskStatusRunning, w.id, nil)
+	w.tm.triggerHooks(func(h TaskHook) error {
+		return h.OnTaskStart(task)
+	})
+
+	w.tm.metrics.incActive()
+	traceCtx, endTrace := w.tm.startTrace(ctx, task, job)
+	result := w.runTask(traceCtx, task, job, lock, slots)
+	endTrace(result)
+	w.tm.metrics.decActive()
+
+	if result.Status == TaskStatusCancelled {
+		w.tm.metrics.observeResult(result)
+		w.tm.finishCancelled(bg, job, w.id, result)
+		return
+	}
+
+	// 关闭超时被强制取消的任务放回队列, 不计入失败
+	if result.Status != TaskStatusCompleted && ctx.Err() != nil {
+		ack, rearm = false, false
+		w.tm.requeue(bg, w.id, job)
+		return
+	}
+	w.tm.metrics.observeResult(result)
+
+	switch {
+	case result.PanicError != nil:
+		w.tm.triggerHooks(func(h TaskHook) error {
+			return h.OnTaskPanic(task, result)
+		})
+	case result.Error != nil:
+		w.tm.triggerHooks(func(h TaskHook) error {
+			return h.OnTaskFail(task, result)
+		})
+	default:
+		w.tm.triggerHooks(func(h TaskHook) error {
+			return h.OnTaskComplete(task, result)
+		})
+	}
+
+	if result.Status != TaskStatusCompleted {
+		if delay, ok := w.tm.retryJob(bg, task, job, result); ok {
+			retry := *job
+			retry.Retried++
+			w.tm.metrics.observeRetry(task.GetID())
+			w.tm.setStatus(bg, &retry, TaskStatusPending, "", result)
+			w.tm.triggerHooks(func(h TaskHook) error {
+				if rh, ok := h.(RetryHook); ok {
+					return rh.OnTaskRetry(task, result, delay)
+				}
+				return nil
+			})
+			return
+		}
+		w.tm.deadLetter(bg, job, result)
+	}
+
+	w.tm.setStatus(bg, job, result.Status, w.id, result)
+	w.tm.recordResult(bg, job, w.id, result)
+
+	if job.Workflow != "" {
+		w.tm.advanceWorkflow(bg, job, result)
+	}
+	w.tm.finishJob(bg, job)
+}
+
+// runTask 在超时控制与锁续期下执行任务并捕获 panic
+func (w *Worker) runTask(ctx context.Context, task Task, job *Job, lock *taskLock, slots *concurrencySlots) (result *TaskResult) {
+	result = &TaskResult{
 		TaskID:    task.GetID(),
+		JobID:     job.ID,
+		Attempt:   job.Retried + 1,
 		StartTime: time.Now(),
 	}
 
@@ -69,43 +388,59 @@ func (w *Worker) executeTask(ctx context.Context, task Task) {
 			result.Status = TaskStatusFailed
 			result.PanicError = r
 			result.StackTrace = debug.Stack()
-			w.tm.triggerHooks(func(h TaskHook) error {
-				return h.OnTaskPanic(task, result)
-			})
 		}
 	}()
 
-	// 获取任务锁
-	lockKey := w.tm.keyManager.TaskLockKey(task.GetID())
-	locked, err := w.tm.acquireLock(ctx, lockKey)
-	if err != nil || !locked {
-		result.Status = TaskStatusFailed
-		result.Error = ErrTaskLockFailed
-		return
-	}
+	// 执行超时由中间件链最内层的 execute 控制, 以便 Timeout 中间件覆盖
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	// 执行任务
-	w.tm.triggerHooks(func(h TaskHook) error {
-		return h.OnTaskStart(task)
-	})
+	// 收到取消请求时取消执行
+	running := w.tm.trackRunning(job.ID, cancel)
+	defer w.tm.untrackRunning(job.ID, running)
 
-	taskCtx, cancel := context.WithTimeout(ctx, task.GetConfig().(*BaseTaskConfig).Timeout)
-	defer cancel()
+	// 锁被他人持有时取消执行, 避免同一任务在多个节点上并发运行
+	stopKeepAlive := lock.keepAlive(taskCtx, cancel)
+	defer stopKeepAlive()
+	if slots != nil {
+		// 并发名额失效时同样取消执行, 避免超出限制
+		stopSlots := keepAlive(taskCtx, slots.ttl, slots.renew, cancel)
+		defer stopSlots()
+	}
+
+	payload := Payload{data: job.Payload, codec: w.tm.codec}
+	taskCtx = withPayload(taskCtx, payload)
+	output := &resultHolder{codec: w.tm.codec}
+	taskCtx = withResultHolder(taskCtx, output)
+	taskCtx = withProgressReporter(taskCtx, &ProgressReporter{tm: w.tm, task: task, job: job})
+	if job.Workflow != "" {
+		taskCtx = context.WithValue(taskCtx, upstreamContextKey{}, w.tm.upstreamResults(taskCtx, job))
+	}
 
-	err = task.Execute(taskCtx)
+	err := w.tm.handler(taskCtx, task, job)
 
+	result.Output = output.bytes()
+	if running.isCancelled() {
+		result.Status = TaskStatusCancelled
+		result.Error = ErrJobCancelled
+		return result
+	}
 	if err != nil {
 		result.Status = TaskStatusFailed
 		result.Error = err
-		w.tm.triggerHooks(func(h TaskHook) error {
-			return h.OnTaskFail(task, result)
-		})
+		if errors.Is(err, ErrTaskTimeout) {
+			result.Status = TaskStatusTimeout
+		}
+		// Recovery 中间件捕获的 panic 与链外捕获的一样记录
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			result.PanicError = panicErr.Value
+			result.StackTrace = panicErr.Stack
+		}
 	} else {
 		result.Status = TaskStatusCompleted
-		w.tm.triggerHooks(func(h TaskHook) error {
-			return h.OnTaskComplete(task, result)
-		})
 	}
+	return result
 }
 
 func (w *Worker) heartbeat(ctx context.Context) {
@@ -113,18 +448,28 @@ func (w *Worker) heartbeat(ctx context.Context) {
 	defer ticker.Stop()
 
 	heartbeatKey := w.tm.keyManager.WorkerHeartbeatKey(w.id)
+	beat := func() {
+		now := time.Now()
+		w.tm.broker.Set(ctx,
+			heartbeatKey,
+			strconv.FormatInt(now.Unix(), 10),
+			time.Second*defaultHeartbeatTTL)
+		// 登记到集群 Worker 注册表, 供 reaper 发现心跳过期的 Worker
+		w.tm.broker.Schedule(ctx, w.tm.keyManager.WorkerRegistryKey(), w.id, now)
+	}
 
+	// 启动时立即上报, 避免第一个周期内无法被派发任务
+	beat()
+
+	// 停止领取任务后仍需持续上报, 避免执行中的任务被 reaper 回收, 直到 ctx 结束
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-w.stopCh:
+		case <-w.heartbeatStop:
 			return
 		case <-ticker.C:
-			w.tm.redis.Set(ctx,
-				heartbeatKey,
-				time.Now().Unix(),
-				time.Second*defaultHeartbeatTTL)
+			beat()
 		}
 	}
 }
@@ -0,0 +1,552 @@
+package taskx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FailurePolicy 工作流节点失败(重试耗尽)后的处理方式
+type FailurePolicy int
+
+const (
+	// AbortOnFailure 任一节点失败后不再投递新的节点, 已投递或执行中的节点通过 Cancel 取消, 工作流立即失败
+	AbortOnFailure FailurePolicy = iota
+	// ContinueOnFailure 跳过失败节点的所有下游节点, 其他分支继续执行
+	ContinueOnFailure
+)
+
+type WorkflowStatus string
+
+const (
+	WorkflowRunning   WorkflowStatus = "running"
+	WorkflowCompleted WorkflowStatus = "completed"
+	WorkflowFailed    WorkflowStatus = "failed"
+)
+
+type WorkflowNodeStatus string
+
+const (
+	// NodeWaiting 等待上游节点完成
+	NodeWaiting   WorkflowNodeStatus = "waiting"
+	NodeEnqueued  WorkflowNodeStatus = "enqueued"
+	NodeCompleted WorkflowNodeStatus = "completed"
+	NodeFailed    WorkflowNodeStatus = "failed"
+	// NodeSkipped 因上游失败或工作流中止而不会执行
+	NodeSkipped WorkflowNodeStatus = "skipped"
+	// NodeCancelled 已投递或执行中, 因工作流中止而被取消
+	NodeCancelled WorkflowNodeStatus = "cancelled"
+)
+
+func (s WorkflowNodeStatus) terminal() bool {
+	return s == NodeCompleted || s == NodeFailed || s == NodeSkipped || s == NodeCancelled
+}
+
+// WorkflowNode 工作流中的一个节点, 执行一个已注册的任务
+type WorkflowNode struct {
+	Name   string
+	TaskID string
+	// After 上游节点, 全部完成后才会投递本节点
+	After []string
+	// Payload 节点参数, 由 TaskManager 的编解码器编码
+	Payload interface{}
+	// RawPayload 已编码的节点参数, 优先于 Payload
+	RawPayload []byte
+	// Queue 投递的队列, 为空时使用任务配置的队列
+	Queue string
+}
+
+// Workflow 由已注册任务组成的有向无环图
+type Workflow struct {
+	// ID 为空时自动生成
+	ID     string
+	Policy FailurePolicy
+	Nodes  []*WorkflowNode
+}
+
+func NewWorkflow() *Workflow {
+	return &Workflow{}
+}
+
+// Add 添加一个节点, after 为上游节点名称, 返回的节点可以继续设置参数
+func (w *Workflow) Add(name, taskID string, after ...string) *WorkflowNode {
+	node := &WorkflowNode{Name: name, TaskID: taskID, After: after}
+	w.Nodes = append(w.Nodes, node)
+	return node
+}
+
+// NewChain 依次执行各任务, 每个任务以前一个任务为上游, 节点名称为任务ID
+func NewChain(taskIDs ...string) *Workflow {
+	w := NewWorkflow()
+	var prev []string
+	for _, taskID := range taskIDs {
+		name := w.uniqueName(taskID)
+		w.Add(name, taskID, prev...)
+		prev = []string{name}
+	}
+	return w
+}
+
+// NewGroup 并行执行各任务
+func NewGroup(taskIDs ...string) *Workflow {
+	w := NewWorkflow()
+	for _, taskID := range taskIDs {
+		w.Add(w.uniqueName(taskID), taskID)
+	}
+	return w
+}
+
+// NewChord 并行执行 group 中的任务, 全部完成后执行 callback
+func NewChord(group []string, callback string) *Workflow {
+	w := NewGroup(group...)
+	after := make([]string, 0, len(w.Nodes))
+	for _, node := range w.Nodes {
+		after = append(after, node.Name)
+	}
+	w.Add(w.uniqueName(callback), callback, after...)
+	return w
+}
+
+// uniqueName 以任务ID作为节点名称, 重复时追加序号
+func (w *Workflow) uniqueName(taskID string) string {
+	name := taskID
+	for i := 2; w.node(name) != nil; i++ {
+		name = taskID + "#" + strconv.Itoa(i)
+	}
+	return name
+}
+
+func (w *Workflow) node(name string) *WorkflowNode {
+	for _, node := range w.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// validate 检查节点名称唯一、上游存在且不存在环
+func (w *Workflow) validate() error {
+	if len(w.Nodes) == 0 {
+		return ErrInvalidWorkflow
+	}
+
+	indegree := make(map[string]int, len(w.Nodes))
+	for _, node := range w.Nodes {
+		if node.Name == "" || node.TaskID == "" {
+			return ErrInvalidWorkflow
+		}
+		if _, exists := indegree[node.Name]; exists {
+			return ErrInvalidWorkflow
+		}
+		indegree[node.Name] = len(node.After)
+	}
+
+	downstream := make(map[string][]string)
+	for _, node := range w.Nodes {
+		for _, up := range node.After {
+			if _, exists := indegree[up]; !exists || up == node.Name {
+				return ErrInvalidWorkflow
+			}
+			downstream[up] = append(downstream[up], node.Name)
+		}
+	}
+
+	var ready []string
+	for name, n := range indegree {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	visited := 0
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		visited++
+		for _, down := range downstream[name] {
+			indegree[down]--
+			if indegree[down] == 0 {
+				ready = append(ready, down)
+			}
+		}
+	}
+	if visited != len(w.Nodes) {
+		return ErrInvalidWorkflow
+	}
+	return nil
+}
+
+// WorkflowState 保存在 Redis 中的工作流进度
+type WorkflowState struct {
+	ID         string               `json:"id"`
+	Status     WorkflowStatus       `json:"status"`
+	Policy     FailurePolicy        `json:"policy"`
+	Nodes      []*WorkflowNodeState `json:"nodes"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  time.Time            `json:"updated_at"`
+	FinishedAt time.Time            `json:"finished_at,omitempty"`
+
+	// aborted 本次更新中被中止的节点任务, 保存后在锁外取消
+	aborted []string
+}
+
+type WorkflowNodeState struct {
+	Name    string             `json:"name"`
+	TaskID  string             `json:"task_id"`
+	After   []string           `json:"after,omitempty"`
+	Queue   string             `json:"queue,omitempty"`
+	Payload []byte             `json:"payload,omitempty"`
+	Status  WorkflowNodeStatus `json:"status"`
+	JobID   string             `json:"job_id,omitempty"`
+	// Result 任务通过 SetResult 设置的结果(已编码)
+	Result []byte `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *WorkflowState) node(name string) *WorkflowNodeState {
+	for _, node := range s.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return nil
+}
+
+// SubmitWorkflow 保存工作流并投递所有没有上游的节点
+func (tm *TaskManager) SubmitWorkflow(ctx context.Context, wf *Workflow) (*WorkflowState, error) {
+	if tm.ctx.Err() != nil {
+		return nil, ErrManagerStopped
+	}
+	if err := wf.validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := &WorkflowState{
+		ID:        wf.ID,
+		Status:    WorkflowRunning,
+		Policy:    wf.Policy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if state.ID == "" {
+		state.ID = uuid.New().String()
+	}
+
+	tm.mu.RLock()
+	for _, node := range wf.Nodes {
+		if _, exists := tm.tasks[node.TaskID]; !exists {
+			tm.mu.RUnlock()
+			return nil, ErrTaskNotFound
+		}
+	}
+	tm.mu.RUnlock()
+
+	for _, node := range wf.Nodes {
+		payload := node.RawPayload
+		if payload == nil && node.Payload != nil {
+			data, err := tm.codec.Marshal(node.Payload)
+			if err != nil {
+				return nil, err
+			}
+			payload = data
+		}
+		state.Nodes = append(state.Nodes, &WorkflowNodeState{
+			Name:    node.Name,
+			TaskID:  node.TaskID,
+			After:   node.After,
+			Queue:   node.Queue,
+			Payload: payload,
+			Status:  NodeWaiting,
+		})
+	}
+
+	err := tm.withWorkflowLock(ctx, state.ID, func() error {
+		_, err := tm.broker.Get(ctx, tm.keyManager.WorkflowKey(state.ID))
+		if err == nil {
+			return ErrDuplicateWorkflow
+		}
+		if !errors.Is(err, ErrKeyNotFound) {
+			return err
+		}
+
+		tm.enqueueReadyNodes(ctx, state)
+		return tm.saveWorkflow(ctx, state)
+	})
+	if err != nil {
+		return nil, err
+	}
+	tm.cancelNodes(ctx, state.aborted)
+	return state, nil
+}
+
+// GetWorkflow 查询工作流进度
+func (tm *TaskManager) GetWorkflow(ctx context.Context, id string) (*WorkflowState, error) {
+	data, err := tm.broker.Get(ctx, tm.keyManager.WorkflowKey(id))
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &WorkflowState{}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// advanceWorkflow 在节点最终完成或失败后更新工作流, 并投递已满足依赖的下游节点
+// 同一节点重复完成(至少一次投递)时忽略; 更新多次失败后工作流被标记为失败, 并返回最后一次的错误
+func (tm *TaskManager) advanceWorkflow(ctx context.Context, job *Job, result *TaskResult) error {
+	var err error
+	for i := 0; i < defaultWorkflowAttempts; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * time.Millisecond * defaultWorkflowBackoff)
+		}
+		if err = tm.updateWorkflow(ctx, job, result); err == nil || errors.Is(err, ErrWorkflowNotFound) {
+			return err
+		}
+	}
+
+	tm.failWorkflow(ctx, job, err)
+	return err
+}
+
+func (tm *TaskManager) updateWorkflow(ctx context.Context, job *Job, result *TaskResult) error {
+	var aborted []string
+	err := tm.withWorkflowLock(ctx, job.Workflow, func() error {
+		state, err := tm.GetWorkflow(ctx, job.Workflow)
+		if err != nil {
+			return err
+		}
+		node := state.node(job.Node)
+		if node == nil || node.Status.terminal() {
+			return nil
+		}
+
+		if result.Status == TaskStatusCompleted {
+			node.Status = NodeCompleted
+			node.Result = result.Output
+		} else {
+			node.Status = NodeFailed
+			node.Error = newAttemptRecord(result).Error
+			tm.skipAfterFailure(state, node)
+		}
+
+		if state.Status == WorkflowRunning {
+			tm.enqueueReadyNodes(ctx, state)
+		}
+		if err := tm.saveWorkflow(ctx, state); err != nil {
+			return err
+		}
+		aborted = state.aborted
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	tm.cancelNodes(ctx, aborted)
+	return nil
+}
+
+// failWorkflow 无法更新工作流时将其标记为失败, 避免下游节点永远等待; 此时拿不到锁, 只能尽力写入
+func (tm *TaskManager) failWorkflow(ctx context.Context, job *Job, cause error) error {
+	state, err := tm.GetWorkflow(ctx, job.Workflow)
+	if err != nil {
+		return err
+	}
+	if state.Status != WorkflowRunning {
+		return nil
+	}
+
+	state.Status = WorkflowFailed
+	for _, node := range state.Nodes {
+		switch {
+		case node.Name == job.Node && !node.Status.terminal():
+			node.Status = NodeFailed
+			node.Error = "update workflow: " + cause.Error()
+		case node.Status == NodeWaiting:
+			node.Status = NodeSkipped
+		}
+	}
+	return tm.saveWorkflow(ctx, state)
+}
+
+// skipAfterFailure 按失败策略跳过不再执行的节点, 中止时已投递的节点记入 state.aborted 等待取消
+func (tm *TaskManager) skipAfterFailure(state *WorkflowState, failed *WorkflowNodeState) {
+	if state.Policy == AbortOnFailure {
+		state.Status = WorkflowFailed
+		for _, node := range state.Nodes {
+			switch node.Status {
+			case NodeWaiting:
+				node.Status = NodeSkipped
+			case NodeEnqueued:
+				node.Status = NodeCancelled
+				state.aborted = append(state.aborted, node.JobID)
+			}
+		}
+		return
+	}
+
+	skipped := map[string]bool{failed.Name: true}
+	for changed := true; changed; {
+		changed = false
+		for _, node := range state.Nodes {
+			if node.Status != NodeWaiting {
+				continue
+			}
+			for _, up := range node.After {
+				if skipped[up] {
+					node.Status = NodeSkipped
+					skipped[node.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// cancelNodes 取消被中止的节点任务, 已结束的任务忽略
+// 被取消的节点在工作流中已是最终状态, 取消后的回调不会再改变工作流
+func (tm *TaskManager) cancelNodes(ctx context.Context, jobIDs []string) {
+	for _, id := range jobIDs {
+		tm.Cancel(ctx, id)
+	}
+}
+
+// enqueueReadyNodes 投递上游全部完成的等待节点, 投递失败的节点视为失败
+func (tm *TaskManager) enqueueReadyNodes(ctx context.Context, state *WorkflowState) {
+	for progress := true; progress && state.Status == WorkflowRunning; {
+		progress = false
+		for _, node := range state.Nodes {
+			if node.Status != NodeWaiting || !tm.nodeReady(state, node) {
+				continue
+			}
+
+			opts := []EnqueueOption{WithRawPayload(node.Payload), withWorkflowNode(state.ID, node.Name)}
+			if node.Queue != "" {
+				opts = append(opts, WithQueue(node.Queue))
+			}
+			job, err := tm.enqueueAt(ctx, node.TaskID, time.Time{}, opts...)
+			if err != nil {
+				node.Status = NodeFailed
+				node.Error = err.Error()
+				tm.skipAfterFailure(state, node)
+				progress = true
+				continue
+			}
+			node.Status = NodeEnqueued
+			node.JobID = job.ID
+		}
+	}
+}
+
+func (tm *TaskManager) nodeReady(state *WorkflowState, node *WorkflowNodeState) bool {
+	for _, up := range node.After {
+		if upstream := state.node(up); upstream == nil || upstream.Status != NodeCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// saveWorkflow 保存工作流, 所有节点结束后标记最终状态, 并在 StatusTTL 后过期
+// 运行中的工作流在 WorkflowTTL 内没有任何更新时同样过期, 避免异常中断的工作流永久残留
+func (tm *TaskManager) saveWorkflow(ctx context.Context, state *WorkflowState) error {
+	now := time.Now()
+	state.UpdatedAt = now
+
+	ttl := tm.workflowTTL
+	finished, failed := true, false
+	for _, node := range state.Nodes {
+		if node.Status == NodeWaiting || node.Status == NodeEnqueued {
+			finished = false
+		}
+		if node.Status == NodeFailed {
+			failed = true
+		}
+	}
+	if finished {
+		if failed {
+			state.Status = WorkflowFailed
+		} else {
+			state.Status = WorkflowCompleted
+		}
+	}
+	if state.Status != WorkflowRunning {
+		if state.FinishedAt.IsZero() {
+			state.FinishedAt = now
+		}
+		ttl = tm.statusTTL
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return tm.broker.Set(ctx, tm.keyManager.WorkflowKey(state.ID), string(data), ttl)
+}
+
+// withWorkflowLock 串行化同一工作流的状态更新, 锁被占用时短暂等待后重试
+func (tm *TaskManager) withWorkflowLock(ctx context.Context, id string, fn func() error) error {
+	key := tm.keyManager.WorkflowLockKey(id)
+	deadline := time.Now().Add(tm.lockTTL)
+	for {
+		lock, err := tm.acquireLock(ctx, key)
+		if err == nil {
+			defer lock.release(context.Background())
+			return fn()
+		}
+		if !errors.Is(err, ErrTaskLockFailed) || time.Now().After(deadline) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// upstreamResults 读取节点直接上游的执行结果
+func (tm *TaskManager) upstreamResults(ctx context.Context, job *Job) map[string]Payload {
+	state, err := tm.GetWorkflow(ctx, job.Workflow)
+	if err != nil {
+		return nil
+	}
+	node := state.node(job.Node)
+	if node == nil {
+		return nil
+	}
+
+	results := make(map[string]Payload, len(node.After))
+	for _, up := range node.After {
+		if upstream := state.node(up); upstream != nil {
+			results[up] = Payload{data: upstream.Result, codec: tm.codec}
+		}
+	}
+	return results
+}
+
+func withWorkflowNode(workflowID, node string) EnqueueOption {
+	return func(o *EnqueueOptions) {
+		o.workflow = workflowID
+		o.node = node
+	}
+}
+
+type upstreamContextKey struct{}
+
+// UpstreamResults 返回工作流节点直接上游的执行结果, 键为上游节点名称
+func UpstreamResults(ctx context.Context) map[string]Payload {
+	results, _ := ctx.Value(upstreamContextKey{}).(map[string]Payload)
+	return results
+}
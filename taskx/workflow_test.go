@@ -0,0 +1,233 @@
+package taskx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	wf := NewWorkflow()
+	wf.Add("a", "task")
+	wf.Add("b", "task", "a")
+	assert.NoError(t, wf.validate())
+
+	wf.Add("b", "task")
+	assert.ErrorIs(t, wf.validate(), ErrInvalidWorkflow)
+
+	cyclic := NewWorkflow()
+	cyclic.Add("a", "task", "b")
+	cyclic.Add("b", "task", "a")
+	assert.ErrorIs(t, cyclic.validate(), ErrInvalidWorkflow)
+
+	missing := NewWorkflow()
+	missing.Add("a", "task", "ghost")
+	assert.ErrorIs(t, missing.validate(), ErrInvalidWorkflow)
+
+	chord := NewChord([]string{"resize", "resize"}, "publish")
+	assert.NoError(t, chord.validate())
+	assert.Equal(t, []string{"resize", "resize#2"}, chord.Nodes[2].After)
+
+	chain := NewChain("a", "b", "c")
+	assert.Equal(t, []string{"b"}, chain.Nodes[2].After)
+}
+
+func TestTaskManagerWorkflow(t *testing.T) {
+	tm := newTestManager(t, WithPoolSize(4))
+	ctx := context.Background()
+
+	// 每个节点的结果为参数加上所有上游结果之和
+	sum := func(ctx context.Context) error {
+		var n int
+		if p, ok := PayloadFromContext(ctx); ok && !p.Empty() {
+			if err := p.Decode(&n); err != nil {
+				return err
+			}
+		}
+		for _, up := range UpstreamResults(ctx) {
+			var v int
+			if err := up.Decode(&v); err != nil {
+				return err
+			}
+			n += v
+		}
+		return SetResult(ctx, n)
+	}
+	assert.NoError(t, tm.RegisterTask(&testTask{BaseTaskConfig: BaseTaskConfig{ID: "sum"}, execute: sum}))
+
+	wf := NewWorkflow()
+	wf.Add("a", "sum").Payload = 1
+	wf.Add("b", "sum", "a").Payload = 10
+	wf.Add("c", "sum", "a").Payload = 100
+	wf.Add("d", "sum", "b", "c")
+
+	state, err := tm.SubmitWorkflow(ctx, wf)
+	assert.NoError(t, err)
+	assert.Equal(t, NodeEnqueued, state.node("a").Status)
+	assert.Equal(t, NodeWaiting, state.node("d").Status)
+
+	tm.Start()
+	waitFor(t, 15*time.Second, func() bool {
+		state, err := tm.GetWorkflow(ctx, state.ID)
+		return err == nil && state.Status != WorkflowRunning
+	})
+
+	state, err = tm.GetWorkflow(ctx, state.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, WorkflowCompleted, state.Status)
+	assert.Equal(t, "112", string(state.node("d").Result))
+
+	_, err = tm.GetWorkflow(ctx, "missing")
+	assert.ErrorIs(t, err, ErrWorkflowNotFound)
+}
+
+func TestTaskManagerWorkflowFailurePolicy(t *testing.T) {
+	tm := newTestManager(t)
+	ctx := context.Background()
+
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "ok"},
+		execute:        func(ctx context.Context) error { return nil },
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "broken", RetryCount: -1},
+		execute:        func(ctx context.Context) error { return errors.New("boom") },
+	}))
+
+	wf := NewWorkflow()
+	wf.Policy = ContinueOnFailure
+	wf.Add("fail", "broken")
+	wf.Add("after-fail", "ok", "fail")
+	wf.Add("other", "ok")
+
+	state, err := tm.SubmitWorkflow(ctx, wf)
+	assert.NoError(t, err)
+
+	tm.Start()
+	waitFor(t, 10*time.Second, func() bool {
+		state, err := tm.GetWorkflow(ctx, state.ID)
+		return err == nil && state.Status != WorkflowRunning
+	})
+
+	state, _ = tm.GetWorkflow(ctx, state.ID)
+	assert.Equal(t, WorkflowFailed, state.Status)
+	assert.Equal(t, NodeFailed, state.node("fail").Status)
+	assert.Equal(t, "boom", state.node("fail").Error)
+	assert.Equal(t, NodeSkipped, state.node("after-fail").Status)
+	assert.Equal(t, NodeCompleted, state.node("other").Status)
+
+	wf.Policy = AbortOnFailure
+	_, err = tm.SubmitWorkflow(ctx, wf)
+	assert.NoError(t, err)
+	wf.ID = state.ID
+	_, err = tm.SubmitWorkflow(ctx, wf)
+	assert.ErrorIs(t, err, ErrDuplicateWorkflow)
+}
+
+func TestTaskManagerWorkflowAbort(t *testing.T) {
+	tm := newTestManager(t, WithPoolSize(4))
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	interrupted := make(chan struct{})
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "block"},
+		execute: func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			close(interrupted)
+			return ctx.Err()
+		},
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "broken", RetryCount: -1},
+		execute: func(ctx context.Context) error {
+			<-started
+			return errors.New("boom")
+		},
+	}))
+	assert.NoError(t, tm.RegisterTask(&testTask{
+		BaseTaskConfig: BaseTaskConfig{ID: "ok"},
+		execute:        func(ctx context.Context) error { return nil },
+	}))
+
+	wf := NewWorkflow()
+	wf.Add("fail", "broken")
+	wf.Add("slow", "block")
+	wf.Add("after", "ok", "fail")
+
+	state, err := tm.SubmitWorkflow(ctx, wf)
+	assert.NoError(t, err)
+
+	tm.Start()
+	waitFor(t, 10*time.Second, func() bool {
+		state, err := tm.GetWorkflow(ctx, state.ID)
+		return err == nil && state.Status != WorkflowRunning
+	})
+
+	// 执行中的兄弟节点被取消
+	select {
+	case <-interrupted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("running sibling not cancelled")
+	}
+
+	state, _ = tm.GetWorkflow(ctx, state.ID)
+	assert.Equal(t, WorkflowFailed, state.Status)
+	assert.Equal(t, NodeFailed, state.node("fail").Status)
+	assert.Equal(t, NodeCancelled, state.node("slow").Status)
+	assert.Equal(t, NodeSkipped, state.node("after").Status)
+
+	waitFor(t, 5*time.Second, func() bool {
+		status, err := tm.GetStatus(ctx, state.node("slow").JobID)
+		return err == nil && status.Status == TaskStatusCancelled
+	})
+}
+
+func TestAdvanceWorkflowLockError(t *testing.T) {
+	broker := newFaultyBroker()
+	tm := NewTaskManagerWithBroker(broker, WithWorkerSize(1))
+	t.Cleanup(tm.Stop)
+	ctx := context.Background()
+
+	noop := func(ctx context.Context) error { return nil }
+	assert.NoError(t, tm.RegisterTask(&testTask{BaseTaskConfig: BaseTaskConfig{ID: "a"}, execute: noop}))
+	assert.NoError(t, tm.RegisterTask(&testTask{BaseTaskConfig: BaseTaskConfig{ID: "b"}, execute: noop}))
+	assert.NoError(t, tm.RegisterTask(&testTask{BaseTaskConfig: BaseTaskConfig{ID: "c"}, execute: noop}))
+
+	submit := func() (*WorkflowState, *Job) {
+		state, err := tm.SubmitWorkflow(ctx, NewChain("a", "b", "c"))
+		assert.NoError(t, err)
+		// 运行中的工作流同样设置过期时间
+		mb := broker.Broker.(*MemoryBroker)
+		mb.mu.Lock()
+		expireAt := mb.values[tm.keyManager.WorkflowKey(state.ID)].expireAt
+		mb.mu.Unlock()
+		assert.WithinDuration(t, time.Now().Add(time.Second*defaultWorkflowTTL), expireAt, time.Minute)
+		return state, &Job{ID: state.Nodes[0].JobID, TaskID: "a", Workflow: state.ID, Node: "a"}
+	}
+	completed := &TaskResult{Status: TaskStatusCompleted}
+
+	// 偶发的 Broker 错误重试后成功
+	state, job := submit()
+	broker.failNext("SetNX", tm.keyManager.WorkflowLockKey(state.ID), defaultWorkflowAttempts-1)
+	assert.NoError(t, tm.advanceWorkflow(ctx, job, completed))
+	state, err := tm.GetWorkflow(ctx, state.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, NodeEnqueued, state.Nodes[1].Status)
+
+	// 持续失败时工作流被标记为失败, 不会永远停留在运行中
+	state, job = submit()
+	broker.failNext("SetNX", tm.keyManager.WorkflowLockKey(state.ID), defaultWorkflowAttempts)
+	assert.ErrorIs(t, tm.advanceWorkflow(ctx, job, completed), errInjected)
+	state, err = tm.GetWorkflow(ctx, state.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, WorkflowFailed, state.Status)
+	assert.Equal(t, NodeFailed, state.Nodes[0].Status)
+	assert.Contains(t, state.Nodes[0].Error, errInjected.Error())
+	assert.Equal(t, NodeSkipped, state.Nodes[1].Status)
+	assert.Equal(t, NodeSkipped, state.Nodes[2].Status)
+}